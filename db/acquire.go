@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// maxStalePings bounds how many stale connections one pre-ping discards
+// before giving up, so a database that is actually down fails fast
+const maxStalePings = 3
+
+// awaitConn runs the checks that precede an operation on the primary pool.
+// With Config.ConnAcquireTimeout it waits that long for a free connection,
+// returning ErrPoolExhausted when none becomes available in time. With
+// Config.PingBeforeUse it also pings the connection and discards it if the
+// ping fails, so a connection the server closed while idle is replaced
+// before a query can fail on it.
+//
+// The connection goes straight back to the pool, where the operation's first
+// statement normally picks it up again; the check sheds load and weeds out
+// stale connections rather than reserving one. Calls made inside a
+// transaction already hold a connection and skip the check.
+func (f *Frontend) awaitConn(ctx context.Context) error {
+	timeout := f.config.ConnAcquireTimeout
+	if (timeout <= 0 && !f.config.PingBeforeUse) || f.activeTx(ctx) != nil {
+		return nil
+	}
+
+	acquireCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		acquireCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		conn, err := f.primary().Conn(acquireCtx)
+		if err != nil {
+			return f.acquireError(ctx, err)
+		}
+		if !f.config.PingBeforeUse {
+			if err := conn.Close(); err != nil {
+				return databaseError(err)
+			}
+			return nil
+		}
+
+		err = conn.PingContext(acquireCtx)
+		if err == nil {
+			if err := conn.Close(); err != nil {
+				return databaseError(err)
+			}
+			return nil
+		}
+		discardConn(conn)
+		if ctx.Err() != nil || attempt == maxStalePings {
+			return databaseError(err)
+		}
+		f.logfContext(ctx, "discarded stale connection: %v", sanitizeError(err))
+	}
+}
+
+// acquireError classifies a failure to take a connection from the pool. Only
+// the acquire deadline means exhaustion; the caller's own deadline or
+// cancellation is reported as such.
+func (f *Frontend) acquireError(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil && f.config.ConnAcquireTimeout > 0 {
+		return fmt.Errorf("%w: no connection within %s", ErrPoolExhausted, f.config.ConnAcquireTimeout)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrTimeout, ctx.Err())
+	}
+	return databaseError(err)
+}
+
+// discardConn closes conn's underlying connection instead of returning it to
+// the pool. Returning driver.ErrBadConn from Raw makes database/sql drop it,
+// whatever error the driver reported for the failed ping.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
@@ -0,0 +1,33 @@
+package db
+
+import "context"
+
+// actorKey is the context key used by WithActor
+type actorKey struct{}
+
+// WithActor returns a context recording actor, such as the ID of the
+// authenticated user, as the party performing database operations made with
+// it. The actor only enriches telemetry and audit records; it is never bound
+// into SQL, and reaches query text only as an encoded Config.TagQueries
+// comment.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, if any
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// actor returns the actor for ctx: the value under Config.ActorKey when one
+// is configured, otherwise the value set by WithActor. It returns "" when
+// there is none.
+func (f *Frontend) actor(ctx context.Context) string {
+	if f.config.ActorKey == nil {
+		actor, _ := ActorFromContext(ctx)
+		return actor
+	}
+
+	return contextString(ctx, f.config.ActorKey)
+}
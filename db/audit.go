@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEvent describes one successful write to a user record. It never
+// contains usernames, emails, passwords or other field values.
+type AuditEvent struct {
+	Operation string // public method name, e.g. "DeleteUser"
+	UserID    int64
+	Actor     string // from WithActor or Config.ActorKey; "" when unknown
+	Time      time.Time
+}
+
+// AuditSink receives an AuditEvent for every successful write, after the
+// surrounding transaction has committed. Writes made through a Tx are
+// delivered once ExecuteInTransaction commits, and discarded on rollback.
+// Record errors cannot undo the write, so they are logged rather than
+// returned. Implementations must be safe for concurrent use.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to AuditSink
+type AuditSinkFunc func(ctx context.Context, event AuditEvent) error
+
+// Record calls fn
+func (fn AuditSinkFunc) Record(ctx context.Context, event AuditEvent) error {
+	return fn(ctx, event)
+}
+
+// auditing reports whether writes need audit events at all
+func (f *Frontend) auditing() bool {
+	return f.config.AuditTable != "" || f.config.AuditSink != nil || f.config.NotifyChannel != ""
+}
+
+// auditWrite runs fn against the primary as the write op and audits the user
+// IDs it returns. With Config.AuditTable or Config.NotifyChannel the write and
+// its audit rows or notifications share one transaction, so neither is stored
+// or sent without the other.
+func auditWrite[T any](ctx context.Context, f *Frontend, op string, fn func(q querier) (T, []int64, error)) (T, error) {
+	if f.config.AuditTable == "" && f.config.NotifyChannel == "" {
+		result, ids, err := fn(f.primary())
+		if err == nil {
+			f.deliverAudit(ctx, f.auditEvents(ctx, op, ids))
+		}
+		return result, err
+	}
+
+	var result T
+	err := f.inTransaction(ctx, func(tx *Tx) error {
+		var err error
+		result, err = txAuditWrite(ctx, tx, op, fn)
+		return err
+	})
+	return result, err
+}
+
+// txAuditWrite runs fn within the transaction, writes audit rows and change
+// notifications alongside it and queues the events for delivery after commit
+func txAuditWrite[T any](ctx context.Context, t *Tx, op string, fn func(q querier) (T, []int64, error)) (T, error) {
+	result, ids, err := fn(t.tx)
+	if err != nil {
+		return result, err
+	}
+
+	events := t.f.auditEvents(ctx, op, ids)
+	if err := t.f.insertAudit(ctx, t.tx, events); err != nil {
+		var zero T
+		return zero, err
+	}
+	if err := t.f.notifyChanges(ctx, t.tx, events); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	t.mu.Lock()
+	t.audit = append(t.audit, events...)
+	t.mu.Unlock()
+	return result, nil
+}
+
+// auditExec is auditWrite for writes to a single known user that return only
+// an error
+func (f *Frontend) auditExec(ctx context.Context, op string, userID int64, fn func(q querier) error) error {
+	_, err := auditWrite(ctx, f, op, func(q querier) (struct{}, []int64, error) {
+		return struct{}{}, []int64{userID}, fn(q)
+	})
+	return err
+}
+
+// auditExec is auditExec within the transaction
+func (t *Tx) auditExec(ctx context.Context, op string, userID int64, fn func(q querier) error) error {
+	_, err := txAuditWrite(ctx, t, op, func(q querier) (struct{}, []int64, error) {
+		return struct{}{}, []int64{userID}, fn(q)
+	})
+	return err
+}
+
+// createdUser adapts a core method returning one user for auditWrite
+func createdUser(user *User, err error) (*User, []int64, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, []int64{user.ID}, nil
+}
+
+// createdUsers adapts a core method returning several users for auditWrite
+func createdUsers(users []*User, err error) ([]*User, []int64, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return users, ids, nil
+}
+
+// auditEvents builds one event per user ID, or nil when auditing is off
+func (f *Frontend) auditEvents(ctx context.Context, op string, ids []int64) []AuditEvent {
+	if !f.auditing() || len(ids) == 0 {
+		return nil
+	}
+	actor := f.actor(ctx)
+	now := time.Now()
+	events := make([]AuditEvent, len(ids))
+	for i, id := range ids {
+		events[i] = AuditEvent{Operation: op, UserID: id, Actor: actor, Time: now}
+	}
+	return events
+}
+
+// insertAudit writes events to Config.AuditTable, which must have the
+// columns operation, user_id, actor and occurred_at
+func (f *Frontend) insertAudit(ctx context.Context, q querier, events []AuditEvent) error {
+	if f.config.AuditTable == "" || len(events) == 0 {
+		return nil
+	}
+
+	values := make([]string, len(events))
+	args := make([]any, 0, len(events)*4)
+	for i, e := range events {
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, e.Operation, e.UserID, e.Actor, e.Time)
+	}
+
+	// The table name is validated; values are always bound
+	query := fmt.Sprintf(`INSERT INTO %s (operation, user_id, actor, occurred_at) VALUES %s`,
+		f.config.AuditTable, strings.Join(values, ", "))
+	if _, err := f.exec(ctx, q, query, args...); err != nil {
+		return fmt.Errorf("audit log: %w", databaseError(err))
+	}
+	return nil
+}
+
+// deliverAudit hands committed events to Config.AuditSink
+func (f *Frontend) deliverAudit(ctx context.Context, events []AuditEvent) {
+	if f.config.AuditSink == nil {
+		return
+	}
+	for _, event := range events {
+		if err := f.config.AuditSink.Record(ctx, event); err != nil {
+			f.logfContext(ctx, "audit sink error for %s on user %d: %v", event.Operation, event.UserID, sanitizeError(err))
+		}
+	}
+}
+
+// validateAuditTable checks the configured audit table name
+func validateAuditTable(table string) error {
+	if table != "" && !tableNamePattern.MatchString(table) {
+		return invalidField("AuditTable", CodeInvalidFormat, "invalid audit table name")
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBatchSize caps the number of rows handled by a single batch operation.
+// It keeps multi-row statements well below driver parameter limits
+// (PostgreSQL allows at most 65535 bind parameters per statement).
+const MaxBatchSize = 1000
+
+// NewUser holds the fields required to create a user in a batch
+type NewUser struct {
+	Username string
+	Email    string
+}
+
+// CreateUsers creates all users in a single transaction using one multi-row
+// INSERT. Every element is validated before the database is touched; a
+// validation failure reports the index of the offending element. Either all
+// users are created or none are.
+func (f *Frontend) CreateUsers(ctx context.Context, users []NewUser) ([]*User, error) {
+	if err := f.validateNewUsers(users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return []*User{}, nil
+	}
+
+	return instrumentResult(ctx, f, "CreateUsers", func(ctx context.Context) ([]*User, error) {
+		var created []*User
+		err := f.inTransaction(ctx, func(tx *Tx) error {
+			var err error
+			created, err = tx.insertUsers(ctx, users)
+			return err
+		})
+		return created, err
+	})
+}
+
+// CreateUsers creates all users within the transaction using one multi-row INSERT
+func (t *Tx) CreateUsers(ctx context.Context, users []NewUser) ([]*User, error) {
+	if err := t.f.validateNewUsers(users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return []*User{}, nil
+	}
+	return t.insertUsers(ctx, users)
+}
+
+// insertUsers inserts pre-validated users within the transaction and audits them
+func (t *Tx) insertUsers(ctx context.Context, users []NewUser) ([]*User, error) {
+	return txAuditWrite(ctx, t, "CreateUsers", func(q querier) ([]*User, []int64, error) {
+		return createdUsers(t.f.insertUsers(ctx, q, users))
+	})
+}
+
+// validateNewUsers validates every element of a batch and enforces MaxBatchSize
+func (f *Frontend) validateNewUsers(users []NewUser) error {
+	if len(users) > MaxBatchSize {
+		return fmt.Errorf("%w: batch exceeds %d users", ErrInvalidInput, MaxBatchSize)
+	}
+	return f.validateUserFields(users)
+}
+
+// validateUserFields applies the CreateUser rules to every element,
+// reporting the index of the first invalid one
+func (f *Frontend) validateUserFields(users []NewUser) error {
+	for i, u := range users {
+		if err := f.validateNewUsername(u.Username); err != nil {
+			return fmt.Errorf("user %d: %w", i, err)
+		}
+		if err := f.validateNewEmail(u.Email); err != nil {
+			return fmt.Errorf("user %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// insertUsers inserts pre-validated users. Drivers with RETURNING get a single
+// multi-row statement; others fall back to one INSERT per row so each
+// generated ID can be read from LastInsertId.
+func (f *Frontend) insertUsers(ctx context.Context, q querier, users []NewUser) ([]*User, error) {
+	if !f.config.supportsReturning() {
+		created := make([]*User, 0, len(users))
+		for _, u := range users {
+			user, err := f.createUser(ctx, q, u.Username, u.Email)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, user)
+		}
+		return created, nil
+	}
+
+	if err := f.requireTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	s := f.schema
+	columns, _ := f.tenantInsertColumns(f.insertColumns(), nil)
+
+	now := time.Now()
+	values := make([]string, 0, len(users))
+	args := make([]any, 0, len(users)*len(columns))
+	for _, u := range users {
+		row := []any{f.normalizeUsername(u.Username), f.normalizeEmail(u.Email), now}
+		if f.config.OptimisticLocking {
+			row = append(row, int64(1))
+		}
+		placeholders := make([]string, len(row))
+		for i := range row {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		_, placeholders = f.tenantInsertColumns(nil, placeholders)
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, row...)
+	}
+
+	// Placeholders are generated, values are always bound as parameters
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s RETURNING %s`,
+		s.Table, strings.Join(columns, ", "), strings.Join(values, ", "), f.userColumns())
+
+	rows, err := f.query(ctx, q, query, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, duplicateError()
+		}
+		return nil, databaseError(err)
+	}
+
+	return f.collectUsers(ctx, rows)
+}
+
+// GetUsersByIDs fetches all users with the given IDs in one query. Duplicate
+// IDs are ignored and missing users are simply absent, so fewer users than
+// requested may be returned. Results follow the order of ids.
+func (f *Frontend) GetUsersByIDs(ctx context.Context, ids []int64) ([]*User, error) {
+	return instrumentResult(ctx, f, "GetUsersByIDs", func(ctx context.Context) ([]*User, error) {
+		return f.getUsersByIDs(ctx, f.reader(), ids)
+	})
+}
+
+// GetUsersByIDs fetches all users with the given IDs within the transaction
+func (t *Tx) GetUsersByIDs(ctx context.Context, ids []int64) ([]*User, error) {
+	return t.f.getUsersByIDs(ctx, t.tx, ids)
+}
+
+// GetUsersByIDsMap is GetUsersByIDs keyed by user ID, for hydrating
+// references. Missing IDs are simply absent from the map.
+func (f *Frontend) GetUsersByIDsMap(ctx context.Context, ids []int64) (map[int64]*User, error) {
+	return instrumentResult(ctx, f, "GetUsersByIDsMap", func(ctx context.Context) (map[int64]*User, error) {
+		return f.getUsersByIDsMap(ctx, f.reader(), ids)
+	})
+}
+
+// GetUsersByIDsMap fetches users keyed by ID within the transaction
+func (t *Tx) GetUsersByIDsMap(ctx context.Context, ids []int64) (map[int64]*User, error) {
+	return t.f.getUsersByIDsMap(ctx, t.tx, ids)
+}
+
+// getUsersByIDs selects users by ID set and orders them like the input
+func (f *Frontend) getUsersByIDs(ctx context.Context, q querier, ids []int64) ([]*User, error) {
+	ids, err := uniqueIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID, err := f.selectUsersByIDs(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore input order
+	users := make([]*User, 0, len(byID))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// getUsersByIDsMap selects users by ID set into a map keyed by ID
+func (f *Frontend) getUsersByIDsMap(ctx context.Context, q querier, ids []int64) (map[int64]*User, error) {
+	ids, err := uniqueIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	return f.selectUsersByIDs(ctx, q, ids)
+}
+
+// selectUsersByIDs runs the query for a deduplicated, validated ID set
+func (f *Frontend) selectUsersByIDs(ctx context.Context, q querier, ids []int64) (map[int64]*User, error) {
+	if len(ids) == 0 {
+		return map[int64]*User{}, nil
+	}
+
+	s := f.schema
+	match, args := f.idSetClause(s.IDColumn, ids, 1)
+	query := fmt.Sprintf(`SELECT %s FROM %s%s`, f.userColumns(), s.Table, f.where(match))
+
+	rows, err := f.query(ctx, q, query, args...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	found, err := f.collectUsers(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*User, len(found))
+	for _, user := range found {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+// UserUpdate holds the new fields of one user in BatchUpdateUsers
+type UserUpdate struct {
+	ID       int64
+	Username string
+	Email    string
+}
+
+// BatchUpdateUsers overwrites the username and email of every user in
+// updates in a single transaction, with the same validation as UpdateUser.
+// Either all users are updated or none are; a missing user returns
+// ErrNotFound.
+//
+// The rows are locked and updated in ascending ID order whatever the order
+// of updates, so concurrent batches over overlapping users queue behind one
+// another instead of deadlocking. Transactions that lock users in another
+// order can still deadlock with it; PostgreSQL and MySQL then abort one of
+// them, and setting Config.TxMaxRetries retries the batch.
+func (f *Frontend) BatchUpdateUsers(ctx context.Context, updates []UserUpdate) error {
+	sorted, err := f.sortedUpdates(updates)
+	if err != nil || len(sorted) == 0 {
+		return err
+	}
+
+	return f.instrument(ctx, "BatchUpdateUsers", func(ctx context.Context) error {
+		return f.inTransaction(ctx, func(tx *Tx) error {
+			return tx.updateSorted(ctx, sorted)
+		})
+	})
+}
+
+// BatchUpdateUsers updates every user in updates in ascending ID order
+// within the transaction
+func (t *Tx) BatchUpdateUsers(ctx context.Context, updates []UserUpdate) error {
+	sorted, err := t.f.sortedUpdates(updates)
+	if err != nil || len(sorted) == 0 {
+		return err
+	}
+	return t.updateSorted(ctx, sorted)
+}
+
+// updateSorted locks and updates the already validated users in sorted
+func (t *Tx) updateSorted(ctx context.Context, sorted []UserUpdate) error {
+	f := t.f
+	_, err := txAuditWrite(ctx, t, "BatchUpdateUsers", func(q querier) (struct{}, []int64, error) {
+		ids := make([]int64, len(sorted))
+		for i, u := range sorted {
+			ids[i] = u.ID
+		}
+		if err := f.lockUsers(ctx, q, ids); err != nil {
+			return struct{}{}, nil, err
+		}
+		for _, u := range sorted {
+			if err := f.updateUser(ctx, q, u.ID, u.Username, u.Email); err != nil {
+				return struct{}{}, nil, err
+			}
+		}
+		return struct{}{}, ids, nil
+	})
+	return err
+}
+
+// sortedUpdates validates updates, reporting the index of the first invalid
+// element, and returns a copy sorted by ID
+func (f *Frontend) sortedUpdates(updates []UserUpdate) ([]UserUpdate, error) {
+	if len(updates) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch exceeds %d users", ErrInvalidInput, MaxBatchSize)
+	}
+	seen := make(map[int64]struct{}, len(updates))
+	for i, u := range updates {
+		if u.ID <= 0 {
+			return nil, fmt.Errorf("update %d: %w: IDs must be positive", i, ErrInvalidInput)
+		}
+		if _, ok := seen[u.ID]; ok {
+			return nil, fmt.Errorf("update %d: %w: duplicate ID %d", i, ErrInvalidInput, u.ID)
+		}
+		seen[u.ID] = struct{}{}
+		if err := f.validateNewUsername(u.Username); err != nil {
+			return nil, fmt.Errorf("update %d: %w", i, err)
+		}
+		if err := f.validateNewEmail(u.Email); err != nil {
+			return nil, fmt.Errorf("update %d: %w", i, err)
+		}
+	}
+
+	sorted := slices.Clone(updates)
+	slices.SortFunc(sorted, func(a, b UserUpdate) int { return cmp.Compare(a.ID, b.ID) })
+	return sorted, nil
+}
+
+// lockUsers locks the rows of ids, which must be sorted, in ascending ID
+// order and returns ErrNotFound for the first ID without a row. PostgreSQL
+// locks rows as the sorted result is produced, and MySQL as it walks the
+// primary key, so ORDER BY fixes the lock order for both.
+func (f *Frontend) lockUsers(ctx context.Context, q querier, ids []int64) error {
+	s := f.schema
+	match, args := f.idSetClause(s.IDColumn, ids, 1)
+	query := fmt.Sprintf(`SELECT %s FROM %s%s ORDER BY %s%s`,
+		s.IDColumn, s.Table, f.where(match), s.IDColumn, f.config.forUpdate())
+	rows, err := f.query(ctx, q, query, args...)
+	if err != nil {
+		return databaseError(err)
+	}
+	locked, err := collectIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if i >= len(locked) || locked[i] != id {
+			return userNotFound(id)
+		}
+	}
+	return nil
+}
+
+// DeleteUsers deletes every user in ids with a single statement and returns
+// how many rows were deleted. IDs are validated and deduplicated like
+// GetUsersByIDs; missing users are skipped rather than reported. With
+// Config.SoftDelete the whole set is soft-deleted instead, and users that
+// are already deleted are not counted.
+func (f *Frontend) DeleteUsers(ctx context.Context, ids []int64) (int64, error) {
+	return instrumentResult(ctx, f, "DeleteUsers", func(ctx context.Context) (int64, error) {
+		return auditWrite(ctx, f, "DeleteUsers", func(q querier) (int64, []int64, error) {
+			return f.deleteUsers(ctx, q, ids)
+		})
+	})
+}
+
+// DeleteUsers deletes every user in ids within the transaction
+func (t *Tx) DeleteUsers(ctx context.Context, ids []int64) (int64, error) {
+	return txAuditWrite(ctx, t, "DeleteUsers", func(q querier) (int64, []int64, error) {
+		return t.f.deleteUsers(ctx, q, ids)
+	})
+}
+
+// deleteUsers deletes an ID set and returns the count and the deleted IDs
+func (f *Frontend) deleteUsers(ctx context.Context, q querier, ids []int64) (int64, []int64, error) {
+	ids, err := uniqueIDs(ids)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	match, args := f.idSetClause(f.schema.IDColumn, ids, 1)
+	return f.deleteMatching(ctx, q, match, args)
+}
+
+// deleteMatching hard- or soft-deletes the users satisfying match, a
+// condition built from schema identifiers with args bound from $1. It returns
+// the number of rows affected and, when they are needed for auditing, their
+// IDs: read with RETURNING where supported, or with a SELECT of the same
+// condition first.
+func (f *Frontend) deleteMatching(ctx context.Context, q querier, match string, args []any) (int64, []int64, error) {
+	s := f.schema
+	query, queryArgs := "", args
+	if f.config.SoftDelete {
+		query = fmt.Sprintf(`UPDATE %s SET %s = $%d%s`, s.Table, s.DeletedAtColumn, len(args)+1, f.where(match))
+		queryArgs = append(append([]any(nil), args...), time.Now())
+	} else {
+		query = fmt.Sprintf(`DELETE FROM %s%s`, s.Table, f.scoped(match))
+	}
+
+	if f.config.supportsReturning() {
+		rows, err := f.query(ctx, q, query+" RETURNING "+s.IDColumn, queryArgs...)
+		if err != nil {
+			return 0, nil, databaseError(err)
+		}
+		ids, err := collectIDs(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+		countRows(ctx, int64(len(ids)))
+		return int64(len(ids)), ids, nil
+	}
+
+	var ids []int64
+	if f.auditing() {
+		selectQuery := fmt.Sprintf(`SELECT %s FROM %s%s`, s.IDColumn, s.Table, f.where(match))
+		rows, err := f.query(ctx, q, selectQuery, args...)
+		if err != nil {
+			return 0, nil, databaseError(err)
+		}
+		if ids, err = collectIDs(rows); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	result, err := f.execCounted(ctx, q, query, queryArgs...)
+	if err != nil {
+		return 0, nil, databaseError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, databaseError(err)
+	}
+	return affected, ids, nil
+}
+
+// collectIDs reads a single-column result of user IDs and closes rows
+func collectIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, databaseError(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return ids, nil
+}
+
+// uniqueIDs validates that every ID is positive, removes duplicates while
+// preserving order, and enforces MaxBatchSize
+func uniqueIDs(ids []int64) ([]int64, error) {
+	seen := make(map[int64]struct{}, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, fmt.Errorf("%w: IDs must be positive", ErrInvalidInput)
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch exceeds %d IDs", ErrInvalidInput, MaxBatchSize)
+	}
+	return unique, nil
+}
+
+// idSetClause returns a condition matching column against ids, with
+// placeholders numbered from pos. PostgreSQL binds the whole set as a single
+// array parameter; other drivers get an IN list. The array literal is built
+// from integers only, so it cannot carry injected SQL.
+func (f *Frontend) idSetClause(column string, ids []int64, pos int) (string, []any) {
+	if f.config.driver() == DriverPostgres {
+		parts := make([]string, len(ids))
+		for i, id := range ids {
+			parts[i] = strconv.FormatInt(id, 10)
+		}
+		return fmt.Sprintf("%s = ANY($%d::bigint[])", column, pos), []any{"{" + strings.Join(parts, ",") + "}"}
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", pos+i)
+		args[i] = id
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), args
+}
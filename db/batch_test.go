@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBatchUpdateUsersValidatesBeforeTransaction(t *testing.T) {
+	db, store := newFakeDB(t)
+	f, err := NewFrontendWithDB(db, DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		updates []UserUpdate
+	}{
+		{"non-positive ID", []UserUpdate{{ID: 0, Username: "alice", Email: "alice@example.com"}}},
+		{"duplicate ID", []UserUpdate{
+			{ID: 1, Username: "alice", Email: "alice@example.com"},
+			{ID: 1, Username: "bob", Email: "bob@example.com"},
+		}},
+		{"invalid email", []UserUpdate{{ID: 1, Username: "alice", Email: "not-an-email"}}},
+	}
+	for _, tt := range tests {
+		if err := f.BatchUpdateUsers(context.Background(), tt.updates); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: BatchUpdateUsers = %v, want ErrInvalidInput", tt.name, err)
+		}
+	}
+	if err := f.BatchUpdateUsers(context.Background(), nil); err != nil {
+		t.Errorf("BatchUpdateUsers(nil) = %v, want nil", err)
+	}
+	if store.begins != 0 {
+		t.Errorf("%d transactions opened for batches that could not succeed", store.begins)
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// cancellable runs fn on a dedicated connection from db and, when
+// Config.CancelSlowQueriesAfter is set, cancels its server-side statement
+// with pg_cancel_backend if ctx is done once the call has run for at least
+// that long. Drivers abandon a cancelled call but may leave the backend
+// working on it; the explicit cancel frees it at once. Other drivers, and a
+// zero threshold, run fn on db directly.
+func cancellable[T any](ctx context.Context, f *Frontend, db *sql.DB, fn func(q querier) (T, error)) (T, error) {
+	threshold := f.config.CancelSlowQueriesAfter
+	if threshold <= 0 || f.config.driver() != DriverPostgres || ctx.Value(dryRunKey{}) != nil {
+		return fn(db)
+	}
+
+	var zero T
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return zero, databaseError(err)
+	}
+	defer conn.Close()
+
+	var pid int64
+	if err := conn.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&pid); err != nil {
+		return zero, databaseError(err)
+	}
+
+	start := time.Now()
+	stop := make(chan struct{})
+	var watcher sync.WaitGroup
+	watcher.Add(1)
+	go func() {
+		defer watcher.Done()
+		select {
+		case <-stop:
+		case <-ctx.Done():
+			if time.Since(start) >= threshold {
+				f.cancelBackend(ctx, db, pid)
+			}
+		}
+	}()
+
+	result, err := fn(conn)
+
+	// The connection must not return to the pool while a cancel for its
+	// backend could still be sent
+	close(stop)
+	watcher.Wait()
+	return result, err
+}
+
+// cancelBackend asks the server to cancel the statement running on pid. It
+// runs on another pooled connection with a fresh context, since the
+// caller's context is done; that is only consulted for the request ID.
+func (f *Frontend) cancelBackend(ctx context.Context, db *sql.DB, pid int64) {
+	cancelCtx, cancel := context.WithTimeout(context.Background(), f.config.connectTimeout())
+	defer cancel()
+
+	if _, err := db.ExecContext(cancelCtx, `SELECT pg_cancel_backend($1)`, pid); err != nil {
+		f.logfContext(ctx, "cancel of backend %d failed: %v", pid, sanitizeError(err))
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchError reports how far BatchExec got before a chunk failed. Chunks
+// before the failing one are committed; the failing chunk is rolled back.
+type BatchError struct {
+	Processed int // IDs in chunks that were committed
+	Err       error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch stopped after %d ids: %v", e.Processed, e.Err)
+}
+
+// Unwrap returns the error of the failing chunk
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchExec splits ids into chunks of at most chunkSize and runs fn on each
+// in its own transaction, committing every chunk before starting the next.
+// This keeps locks short for maintenance over many rows, at the cost of
+// atomicity across chunks. Each chunk is a separate operation with its own
+// query timeout.
+//
+// BatchExec stops at the first chunk whose fn or commit fails, or when ctx is
+// done between chunks, and returns a *BatchError wrapping the cause so
+// callers can resume from ids[Processed:]. fn receives each chunk as it
+// starts, which is also the place to report progress.
+func (f *Frontend) BatchExec(ctx context.Context, ids []int64, chunkSize int, fn func(tx *Tx, chunk []int64) error) error {
+	if chunkSize <= 0 || chunkSize > MaxBatchSize {
+		return fmt.Errorf("%w: chunk size must be 1-%d", ErrInvalidInput, MaxBatchSize)
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: batch function is required", ErrInvalidInput)
+	}
+	for _, id := range ids {
+		if id <= 0 {
+			return fmt.Errorf("%w: IDs must be positive", ErrInvalidInput)
+		}
+	}
+
+	for start := 0; start < len(ids); start += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return &BatchError{Processed: start, Err: err}
+		}
+
+		chunk := ids[start:min(start+chunkSize, len(ids))]
+		err := f.instrument(ctx, "BatchExec", func(ctx context.Context) error {
+			return f.inTransaction(ctx, func(tx *Tx) error {
+				return fn(tx, chunk)
+			})
+		})
+		if err != nil {
+			return &BatchError{Processed: start, Err: err}
+		}
+	}
+	return nil
+}
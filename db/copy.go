@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BulkCopyUsers loads users with PostgreSQL's COPY protocol in one
+// transaction and returns how many rows were copied. It is much faster than
+// CreateUsers for large imports and has no MaxBatchSize limit, but returns
+// no rows: the copied users' IDs are not known. Every element is validated
+// before the database is touched, and either all users are copied or none
+// are.
+//
+// COPY goes through the driver's prepared COPY FROM STDIN support, as
+// provided by github.com/lib/pq; PostgreSQL drivers without it fail to
+// prepare the statement. Other databases return ErrUnsupported, as do
+// configurations with Config.AuditTable, AuditSink or NotifyChannel, since
+// audit events need the IDs COPY does not report.
+func (f *Frontend) BulkCopyUsers(ctx context.Context, users []NewUser) (int64, error) {
+	if err := f.validateBulkCopy(users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, nil
+	}
+
+	return instrumentResult(ctx, f, "BulkCopyUsers", func(ctx context.Context) (int64, error) {
+		var copied int64
+		err := f.inTransaction(ctx, func(tx *Tx) error {
+			var err error
+			copied, err = tx.copyUsers(ctx, users)
+			return err
+		})
+		return copied, err
+	})
+}
+
+// BulkCopyUsers loads users with COPY within the transaction
+func (t *Tx) BulkCopyUsers(ctx context.Context, users []NewUser) (int64, error) {
+	if err := t.f.validateBulkCopy(users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, nil
+	}
+	return t.copyUsers(ctx, users)
+}
+
+// copyUsers streams pre-validated users through COPY FROM STDIN. Each Exec
+// with arguments buffers one row; the final Exec without arguments ends the
+// copy and reports the row count or the first error.
+func (t *Tx) copyUsers(ctx context.Context, users []NewUser) (int64, error) {
+	f := t.f
+	if err := f.requireTenant(ctx); err != nil {
+		return 0, err
+	}
+
+	s := f.schema
+	columns := f.insertColumns()
+	tenant, _ := TenantFromContext(ctx)
+	if s.TenantColumn != "" {
+		columns = append(columns, s.TenantColumn)
+	}
+	// Identifiers come from the validated schema; values are streamed
+	query := fmt.Sprintf(`COPY %s (%s) FROM STDIN`, s.Table, strings.Join(columns, ", "))
+	if _, ok := f.recordDryRun(ctx, t.tx, query, nil); ok {
+		return 0, errDryRun
+	}
+	f.logStatement(ctx, query, nil)
+
+	stmt, err := t.tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, databaseError(err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, u := range users {
+		row := []any{f.normalizeUsername(u.Username), f.normalizeEmail(u.Email), now}
+		if f.config.OptimisticLocking {
+			row = append(row, int64(1))
+		}
+		if s.TenantColumn != "" {
+			row = append(row, tenant)
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, copyError(err)
+		}
+	}
+
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, copyError(err)
+	}
+	copied, err := result.RowsAffected()
+	if err != nil {
+		return 0, databaseError(err)
+	}
+	countRows(ctx, copied)
+	return copied, nil
+}
+
+// copyError maps a COPY failure, reporting duplicates like CreateUsers
+func copyError(err error) error {
+	if isUniqueViolation(err) {
+		return duplicateError()
+	}
+	return databaseError(err)
+}
+
+// validateBulkCopy checks that COPY can be used and validates every element
+func (f *Frontend) validateBulkCopy(users []NewUser) error {
+	if f.config.driver() != DriverPostgres {
+		return fmt.Errorf("%w: bulk copy requires postgres", ErrUnsupported)
+	}
+	if f.auditing() {
+		return fmt.Errorf("%w: bulk copy cannot be audited", ErrUnsupported)
+	}
+	return f.validateUserFields(users)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// CredentialProvider supplies database credentials on demand, for example
+// from Vault or a cloud secret manager, so rotated passwords are picked up
+// without restarting the process. Credentials is called each time the pool
+// opens a new connection and must be safe for concurrent use. Returned
+// errors are sanitized before being surfaced.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (user, password string, err error)
+}
+
+// CredentialProviderFunc adapts a function to CredentialProvider
+type CredentialProviderFunc func(ctx context.Context) (user, password string, err error)
+
+// Credentials calls fn
+func (fn CredentialProviderFunc) Credentials(ctx context.Context) (string, string, error) {
+	return fn(ctx)
+}
+
+// providerConnector builds a fresh DSN from the provider for every new
+// connection. Existing connections keep the credentials they were opened
+// with until Config.ConnMaxLifetime retires them.
+type providerConnector struct {
+	drv    driver.Driver
+	config *Config
+}
+
+// Connect fetches current credentials and opens one connection with them
+func (c *providerConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	user, password, err := c.config.Credentials.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential provider: %v", ErrConnectionFailed, sanitizeError(err))
+	}
+	if c.config.requiresCredentials() && (user == "" || password == "") {
+		return nil, fmt.Errorf("%w: credential provider returned empty credentials", ErrConnectionFailed)
+	}
+	if c.config.driver() == DriverMySQL {
+		if err := validateMySQLCredentials(user, password); err != nil {
+			return nil, fmt.Errorf("%w: credential provider: %w", ErrConnectionFailed, err)
+		}
+	}
+
+	// Drivers may echo the DSN, and with it the fetched password, when they
+	// fail to parse it
+	dsn := buildDSN(c.config, user, password)
+	if dc, ok := c.drv.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, sanitizeError(err))
+		}
+		return connector.Connect(ctx)
+	}
+	conn, err := c.drv.Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, sanitizeError(err))
+	}
+	return conn, nil
+}
+
+// Driver returns the underlying driver
+func (c *providerConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// openWithProvider opens a pool whose connections authenticate with
+// credentials fetched from Config.Credentials
+func openWithProvider(ctx context.Context, config *Config) (*sql.DB, error) {
+	// sql.Open does not connect, so an empty DSN is enough to look up the
+	// registered driver
+	probe, err := sql.Open(config.driverName(), "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, sanitizeError(err))
+	}
+	drv := probe.Driver()
+	probe.Close()
+
+	db := sql.OpenDB(&providerConnector{drv: drv, config: config})
+	return setupPool(ctx, config, db)
+}
@@ -0,0 +1,264 @@
+// Package dbtest provides an in-memory db.UserStore for unit tests of code
+// that depends on the db package, without a database server.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kushmanmb-org/.github/db"
+)
+
+// FakeStore is an in-memory db.UserStore. It returns the same sentinel errors
+// as Frontend (ErrInvalidInput, ErrNotFound, ErrDuplicate) so callers'
+// error handling can be tested, but it only checks inputs loosely: empty
+// usernames and emails and non-positive IDs are rejected, while the format
+// rules of the real validators are not applied. It is safe for concurrent
+// use.
+type FakeStore struct {
+	mu     sync.Mutex
+	users  map[int64]db.User
+	nextID int64
+}
+
+var _ db.UserStore = (*FakeStore)(nil)
+
+// NewFakeStore returns an empty store. IDs are assigned from 1 upwards.
+func NewFakeStore() *FakeStore {
+	return &FakeStore{users: make(map[int64]db.User), nextID: 1}
+}
+
+// GetUserByID returns the user with userID
+func (s *FakeStore) GetUserByID(ctx context.Context, userID int64) (*db.User, error) {
+	if userID <= 0 {
+		return nil, db.ErrInvalidInput
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, notFound(userID)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername returns the user with an exactly matching username
+func (s *FakeStore) GetUserByUsername(ctx context.Context, username string) (*db.User, error) {
+	if username == "" {
+		return nil, fmt.Errorf("%w: username is required", db.ErrInvalidInput)
+	}
+	return s.findBy(func(u db.User) bool { return u.Username == username })
+}
+
+// GetUserByEmail returns the user with an exactly matching email
+func (s *FakeStore) GetUserByEmail(ctx context.Context, email string) (*db.User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("%w: email is required", db.ErrInvalidInput)
+	}
+	return s.findBy(func(u db.User) bool { return u.Email == email })
+}
+
+// CreateUser stores a new user, rejecting a username or email already in use
+func (s *FakeStore) CreateUser(ctx context.Context, username, email string) (*db.User, error) {
+	if err := validate(username, email); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conflicts(0, username, email) {
+		return nil, duplicate()
+	}
+	user := db.User{ID: s.nextID, Username: username, Email: email, CreatedAt: time.Now()}
+	s.users[user.ID] = user
+	s.nextID++
+	return &user, nil
+}
+
+// SearchUsers returns up to limit users whose username or email contains
+// searchTerm, newest first. The limit is normalized like Frontend's.
+func (s *FakeStore) SearchUsers(ctx context.Context, searchTerm string, limit int) ([]*db.User, error) {
+	if searchTerm == "" || len(searchTerm) > 100 {
+		return nil, db.ErrInvalidInput
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []*db.User
+	for _, user := range s.sorted() {
+		if strings.Contains(user.Username, searchTerm) || strings.Contains(user.Email, searchTerm) {
+			matches = append(matches, user)
+		}
+	}
+	return page(matches, normalizeLimit(limit), 0), nil
+}
+
+// CountUsers returns the number of stored users
+func (s *FakeStore) CountUsers(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.users)), nil
+}
+
+// ListUsers returns a page of users, newest first
+func (s *FakeStore) ListUsers(ctx context.Context, limit, offset int) ([]*db.User, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", db.ErrInvalidInput)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return page(s.sorted(), normalizeLimit(limit), offset), nil
+}
+
+// UpdateUser replaces a user's username and email
+func (s *FakeStore) UpdateUser(ctx context.Context, userID int64, username, email string) error {
+	if userID <= 0 {
+		return db.ErrInvalidInput
+	}
+	if err := validate(username, email); err != nil {
+		return err
+	}
+	return s.update(userID, username, email)
+}
+
+// UpdateUserEmail replaces only a user's email
+func (s *FakeStore) UpdateUserEmail(ctx context.Context, userID int64, email string) error {
+	if userID <= 0 || email == "" {
+		return db.ErrInvalidInput
+	}
+	return s.update(userID, "", email)
+}
+
+// UpdateUserUsername replaces only a user's username
+func (s *FakeStore) UpdateUserUsername(ctx context.Context, userID int64, username string) error {
+	if userID <= 0 || username == "" {
+		return db.ErrInvalidInput
+	}
+	return s.update(userID, username, "")
+}
+
+// DeleteUser removes a user
+func (s *FakeStore) DeleteUser(ctx context.Context, userID int64) error {
+	if userID <= 0 {
+		return db.ErrInvalidInput
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return notFound(userID)
+	}
+	delete(s.users, userID)
+	return nil
+}
+
+// update applies the non-empty fields to an existing user
+func (s *FakeStore) update(userID int64, username, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return notFound(userID)
+	}
+	if username != "" {
+		user.Username = username
+	}
+	if email != "" {
+		user.Email = email
+	}
+	if s.conflicts(userID, user.Username, user.Email) {
+		return duplicate()
+	}
+	s.users[userID] = user
+	return nil
+}
+
+// findBy returns the first user satisfying match in sorted order, newest
+// first. Usernames and emails are unique, so lookups by them match at most
+// one user.
+func (s *FakeStore) findBy(match func(db.User) bool) (*db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.sorted() {
+		if match(*user) {
+			return user, nil
+		}
+	}
+	return nil, db.ErrNotFound
+}
+
+// conflicts reports whether a user other than exceptID already has username
+// or email. The caller holds mu.
+func (s *FakeStore) conflicts(exceptID int64, username, email string) bool {
+	for id, user := range s.users {
+		if id != exceptID && (user.Username == username || user.Email == email) {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns copies of all users, newest first with ties broken by
+// descending ID like Frontend's default ordering. The caller holds mu.
+func (s *FakeStore) sorted() []*db.User {
+	users := make([]*db.User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.After(users[j].CreatedAt)
+		}
+		return users[i].ID > users[j].ID
+	})
+	return users
+}
+
+// page returns users[offset:offset+limit], or an empty slice past the end
+func page(users []*db.User, limit, offset int) []*db.User {
+	if offset >= len(users) {
+		return []*db.User{}
+	}
+	users = users[offset:]
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users
+}
+
+// normalizeLimit mirrors Frontend's limit handling
+func normalizeLimit(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 10
+	}
+	return limit
+}
+
+// validate applies the fake's loose input checks
+func validate(username, email string) error {
+	if username == "" {
+		return fmt.Errorf("%w: username is required", db.ErrInvalidInput)
+	}
+	if email == "" {
+		return fmt.Errorf("%w: email is required", db.ErrInvalidInput)
+	}
+	return nil
+}
+
+// notFound matches the error Frontend returns for a missing user ID
+func notFound(userID int64) error {
+	return &db.NotFoundError{Entity: "user", Key: "id=" + strconv.FormatInt(userID, 10)}
+}
+
+// duplicate matches the error Frontend returns on a unique violation
+func duplicate() error {
+	return fmt.Errorf("%w: %w", db.ErrInvalidInput, db.ErrDuplicate)
+}
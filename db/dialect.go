@@ -0,0 +1,395 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Driver identifies the database backend a Frontend talks to
+type Driver string
+
+// Supported drivers
+const (
+	DriverPostgres Driver = "postgres"
+	DriverMySQL    Driver = "mysql"
+	DriverSQLite   Driver = "sqlite"
+)
+
+// defaultDriverNames maps each Driver to the database/sql driver name
+// registered by the most common driver package for that backend
+var defaultDriverNames = map[Driver]string{
+	DriverPostgres: "postgres", // github.com/lib/pq
+	DriverMySQL:    "mysql",    // github.com/go-sql-driver/mysql
+	DriverSQLite:   "sqlite3",  // github.com/mattn/go-sqlite3
+}
+
+// SSL modes accepted in Config.SSLMode, using libpq semantics
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// mysqlTLSValues maps SSL modes onto go-sql-driver/mysql tls parameter values
+var mysqlTLSValues = map[string]string{
+	SSLModeDisable:    "false",
+	SSLModeRequire:    "skip-verify",
+	SSLModeVerifyCA:   "true",
+	SSLModeVerifyFull: "true",
+}
+
+// driver returns the configured driver, treating an empty value as PostgreSQL
+// so configurations created before Driver existed keep working
+func (c *Config) driver() Driver {
+	if c.Driver == "" {
+		return DriverPostgres
+	}
+	return c.Driver
+}
+
+// driverName returns the name passed to sql.Open
+func (c *Config) driverName() string {
+	if c.DriverName != "" {
+		return c.DriverName
+	}
+	return defaultDriverNames[c.driver()]
+}
+
+// sslMode returns the configured SSL mode, defaulting to require
+func (c *Config) sslMode() string {
+	if c.SSLMode == "" {
+		return SSLModeRequire
+	}
+	return c.SSLMode
+}
+
+// validateSSL rejects unknown SSL modes and certificate options the driver cannot use
+func validateSSL(config *Config) error {
+	if _, ok := mysqlTLSValues[config.sslMode()]; !ok {
+		return invalidField("SSLMode", CodeUnsupported, "unsupported SSL mode")
+	}
+	if config.driver() == DriverMySQL {
+		// go-sql-driver/mysql needs certificates registered via mysql.RegisterTLSConfig
+		paths := []struct{ field, path string }{
+			{"SSLRootCert", config.SSLRootCert}, {"SSLCert", config.SSLCert}, {"SSLKey", config.SSLKey},
+		}
+		for _, p := range paths {
+			if p.path != "" {
+				return invalidField(p.field, CodeUnsupported, "SSL certificate paths are not supported for mysql")
+			}
+		}
+	}
+	if config.SSLCert == "" && config.SSLKey != "" {
+		return invalidField("SSLCert", CodeRequired, "SSL client certificate and key must be set together")
+	}
+	if config.SSLKey == "" && config.SSLCert != "" {
+		return invalidField("SSLKey", CodeRequired, "SSL client certificate and key must be set together")
+	}
+	return nil
+}
+
+// paramKeyPattern matches connection parameter names; it admits the
+// snake_case keys of libpq and the camelCase ones of go-sql-driver/mysql
+var paramKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+
+// reservedParams are the connection parameters buildDSN sets itself from
+// dedicated Config fields
+var reservedParams = map[Driver][]string{
+	DriverPostgres: {"host", "port", "dbname", "user", "password", "sslmode", "sslrootcert", "sslcert", "sslkey", "application_name"},
+	DriverMySQL:    {"parseTime", "tls", "connectionAttributes"},
+}
+
+// validateParams checks Config.Params keys and values. Values are quoted when
+// the DSN is built, so only control characters are rejected.
+func validateParams(config *Config) error {
+	if len(config.Params) == 0 {
+		return nil
+	}
+	if config.driver() == DriverSQLite {
+		return invalidField("Params", CodeUnsupported, "connection parameters are not supported for sqlite")
+	}
+	for key, value := range config.Params {
+		if !paramKeyPattern.MatchString(key) {
+			return invalidField("Params", CodeInvalidFormat, "invalid connection parameter name")
+		}
+		if slices.Contains(reservedParams[config.driver()], key) {
+			return invalidField("Params", CodeNotAllowed, "connection parameter "+key+" is set from Config")
+		}
+		if strings.ContainsFunc(value, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+			return invalidField("Params", CodeInvalidCharacters, "connection parameter "+key+" contains control characters")
+		}
+	}
+	return nil
+}
+
+// sortedParams returns the Config.Params keys in order, so the DSN is stable
+func (c *Config) sortedParams() []string {
+	keys := make([]string, 0, len(c.Params))
+	for key := range c.Params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// maxApplicationNameLength is PostgreSQL's limit; longer names are truncated
+// by the server
+const maxApplicationNameLength = 63
+
+// applicationName returns Config.ApplicationName, or the executable's name
+// made to fit the same rules when it is empty
+func (c *Config) applicationName() string {
+	if c.ApplicationName != "" {
+		return c.ApplicationName
+	}
+	name := strings.Map(func(r rune) rune {
+		if !isApplicationNameChar(r) || r == ',' || r == ':' {
+			return '_'
+		}
+		return r
+	}, filepath.Base(os.Args[0]))
+	if len(name) > maxApplicationNameLength {
+		name = name[:maxApplicationNameLength]
+	}
+	return name
+}
+
+// isApplicationNameChar reports whether r is printable ASCII, the only
+// characters PostgreSQL keeps in application_name
+func isApplicationNameChar(r rune) bool {
+	return r >= 0x20 && r < 0x7f
+}
+
+// validateApplicationName checks an explicit Config.ApplicationName. MySQL
+// separates connection attributes with ',' and ':', so those are rejected
+// there.
+func validateApplicationName(config *Config) error {
+	name := config.ApplicationName
+	if len(name) > maxApplicationNameLength {
+		return invalidField("ApplicationName", CodeTooLong, "application name too long")
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return !isApplicationNameChar(r) }) {
+		return invalidField("ApplicationName", CodeInvalidCharacters, "application name must be printable ASCII")
+	}
+	if config.driver() == DriverMySQL && strings.ContainsAny(name, ",:") {
+		return invalidField("ApplicationName", CodeInvalidCharacters, "application name must not contain ',' or ':' for mysql")
+	}
+	return nil
+}
+
+// requiresCredentials reports whether the driver authenticates with a user and password
+func (c *Config) requiresCredentials() bool {
+	return c.driver() != DriverSQLite
+}
+
+// buildDSN builds the connection string for the configured driver
+func buildDSN(config *Config, user, password string) string {
+	switch config.driver() {
+	case DriverMySQL:
+		return mysqlDSN(config, user, password)
+	case DriverSQLite:
+		return sqliteDSN(config)
+	default:
+		return postgresDSN(config, user, password)
+	}
+}
+
+// postgresDSN builds a libpq key/value connection string
+func postgresDSN(config *Config, user, password string) string {
+	dsn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
+		quoteDSNValue(config.Host), config.Port, quoteDSNValue(config.Database),
+		quoteDSNValue(user), quoteDSNValue(password), config.sslMode())
+
+	// Only send certificate parameters that were provided
+	certs := []struct{ key, value string }{
+		{"sslrootcert", config.SSLRootCert},
+		{"sslcert", config.SSLCert},
+		{"sslkey", config.SSLKey},
+	}
+	for _, cert := range certs {
+		if cert.value != "" {
+			dsn += " " + cert.key + "=" + quoteDSNValue(cert.value)
+		}
+	}
+	if name := config.applicationName(); name != "" {
+		dsn += " application_name=" + quoteDSNValue(name)
+	}
+	for _, key := range config.sortedParams() {
+		dsn += " " + key + "=" + quoteDSNValue(config.Params[key])
+	}
+	return dsn
+}
+
+// mysqlDSN builds a go-sql-driver/mysql connection string. parseTime is
+// required so DATETIME columns scan into time.Time. The driver does not
+// unescape the user, password or database, so they are written as-is after
+// validateMySQLCredentials and validateMySQLDatabase.
+func mysqlDSN(config *Config, user, password string) string {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&tls=%s",
+		user, password, addr, config.Database, mysqlTLSValues[config.sslMode()])
+	if name := config.applicationName(); name != "" {
+		dsn += "&connectionAttributes=" + url.QueryEscape("program_name:"+name)
+	}
+	for _, key := range config.sortedParams() {
+		dsn += "&" + key + "=" + url.QueryEscape(config.Params[key])
+	}
+	return dsn
+}
+
+// validateMySQLCredentials rejects credentials mysqlDSN cannot encode.
+// go-sql-driver/mysql splits its DSN on the first ':' and the last '/'
+// without unescaping, so a ':' in the user or a '/' in either value would
+// connect with different credentials.
+func validateMySQLCredentials(user, password string) error {
+	if strings.ContainsAny(user, ":/") {
+		return invalidField("user", CodeInvalidCharacters, "mysql user must not contain ':' or '/'")
+	}
+	if strings.Contains(password, "/") {
+		return invalidField("password", CodeInvalidCharacters, "mysql password must not contain '/'")
+	}
+	return nil
+}
+
+// validateMySQLDatabase rejects database names mysqlDSN cannot encode: a
+// '/' or '?' would be read as the start of the name or of the parameters
+func validateMySQLDatabase(field, database string) error {
+	if strings.ContainsAny(database, "/?") {
+		return invalidField(field, CodeInvalidCharacters, "mysql database name must not contain '/' or '?'")
+	}
+	return nil
+}
+
+// validateDSN performs a cheap structural check of a caller-supplied
+// connection string. Parse errors are never wrapped because they echo the
+// input, which may contain credentials.
+func validateDSN(driver Driver, dsn string) error {
+	if strings.TrimSpace(dsn) == "" {
+		return fmt.Errorf("%w: DSN is required", ErrInvalidInput)
+	}
+
+	switch driver {
+	case DriverPostgres:
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			if u, err := url.Parse(dsn); err != nil || (u.Host == "" && u.Query().Get("host") == "") {
+				return fmt.Errorf("%w: malformed postgres URL", ErrInvalidInput)
+			}
+		} else if !strings.Contains(dsn, "=") {
+			return fmt.Errorf("%w: DSN must be a postgres URL or key=value list", ErrInvalidInput)
+		}
+	case DriverMySQL:
+		// [user[:password]@][net[(addr)]]/dbname[?params]
+		if !strings.Contains(dsn, "/") {
+			return fmt.Errorf("%w: mysql DSN must include /dbname", ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// sqliteDSN uses the database name as the file path
+func sqliteDSN(config *Config) string {
+	return config.Database
+}
+
+// quoteDSNValue quotes a libpq key/value parameter when it contains
+// characters that would otherwise be parsed as a separator
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// supportsReturning reports whether INSERT ... RETURNING is available
+func (c *Config) supportsReturning() bool {
+	return c.driver() != DriverMySQL
+}
+
+// forUpdate returns the clause that locks selected rows until the transaction
+// ends. SQLite has no row locks, as a write transaction locks the whole
+// database, so no clause is added there.
+func (c *Config) forUpdate() string {
+	if c.driver() == DriverSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// emailDomain returns the expression for the part of column after the last
+// @, as no string function for it is shared by every driver. RFC 5322 emails
+// may contain an @ in a quoted local part, so the domain is taken after the
+// last one, as checkEmailDomain does. SQLite has no reverse search: trimming
+// every character other than @ off the end leaves the local part and its @.
+func (c *Config) emailDomain(column string) string {
+	switch c.driver() {
+	case DriverMySQL:
+		return fmt.Sprintf("SUBSTRING_INDEX(%s, '@', -1)", column)
+	case DriverSQLite:
+		return fmt.Sprintf("SUBSTR(%[1]s, LENGTH(RTRIM(%[1]s, REPLACE(%[1]s, '@', ''))) + 1)", column)
+	default:
+		return fmt.Sprintf("SUBSTRING(%s FROM '[^@]*$')", column)
+	}
+}
+
+// likeEscape returns the ESCAPE clause that makes backslash the LIKE escape
+// character. MySQL already uses backslash by default, and its string
+// literals treat '\' as an escape, so no clause is added there.
+func (c *Config) likeEscape() string {
+	if c.driver() == DriverMySQL {
+		return ""
+	}
+	return ` ESCAPE '\'`
+}
+
+// rebind rewrites PostgreSQL-style $N placeholders into the form expected
+// by the configured driver. Queries in this package are written with $N
+// placeholders; drivers that only understand positional ? markers get the
+// arguments reordered to match, so a placeholder may be referenced more than
+// once. Quoted literals are left untouched.
+func (f *Frontend) rebind(query string, args []any) (string, []any) {
+	if f.config.driver() == DriverPostgres {
+		return query, args
+	}
+
+	var b strings.Builder
+	b.Grow(len(query))
+	rebound := make([]any, 0, len(args))
+	inQuote := false
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			inQuote = !inQuote
+		}
+		if c != '$' || inQuote {
+			b.WriteByte(c)
+			continue
+		}
+
+		j := i + 1
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(query[i+1 : j])
+		if err != nil || n < 1 || n > len(args) {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteByte('?')
+		rebound = append(rebound, args[n-1])
+		i = j - 1
+	}
+
+	return b.String(), rebound
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMySQLRejectsValuesTheDSNCannotEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   func(*Config)
+		user     string
+		password string
+		field    string
+	}{
+		{"slash in password", nil, "app", "pa/ss", "password"},
+		{"colon in user", nil, "ap:p", "secret", "user"},
+		{"slash in user", nil, "ap/p", "secret", "user"},
+		{"slash in database", func(c *Config) { c.Database = "app/other" }, "app", "secret", "Database"},
+		{"question mark in database", func(c *Config) { c.Database = "app?allowAllFiles=true" }, "app", "secret", "Database"},
+		{"slash in replica database", func(c *Config) { c.ReadReplica = &ReadConfig{Database: "a/b"} }, "app", "secret", "ReadReplica.Database"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Driver = DriverMySQL
+			config.Database = "app"
+			if tt.config != nil {
+				tt.config(config)
+			}
+
+			_, err := NewFrontend(config, tt.user, tt.password)
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Field != tt.field || ve.Code != CodeInvalidCharacters {
+				t.Errorf("NewFrontend = %v, want a %s ValidationError", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestMySQLRejectsProviderCredentialsTheDSNCannotEncode(t *testing.T) {
+	config := DefaultConfig()
+	config.Driver = DriverMySQL
+	config.Credentials = CredentialProviderFunc(func(context.Context) (string, string, error) {
+		return "app", "pa/ss", nil
+	})
+
+	c := &providerConnector{drv: dsnEchoDriver{}, config: config}
+	_, err := c.Connect(context.Background())
+	if !errors.Is(err, ErrConnectionFailed) || !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Connect = %v, want ErrConnectionFailed and ErrInvalidInput", err)
+	}
+}
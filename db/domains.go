@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DomainCount is the number of users whose email is at Domain
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Count  int64  `json:"count"`
+}
+
+// EmailDomainCounts returns the most common email domains and how many users
+// have an email at each, largest first. Domains are compared lowercased.
+// limit follows the usual paging rules: 1 to 100, defaulting to 10.
+func (f *Frontend) EmailDomainCounts(ctx context.Context, limit int) ([]DomainCount, error) {
+	return instrumentResult(ctx, f, "EmailDomainCounts", func(ctx context.Context) ([]DomainCount, error) {
+		return f.emailDomainCounts(ctx, f.reader(), limit)
+	})
+}
+
+// EmailDomainCounts returns the most common email domains within the
+// transaction
+func (t *Tx) EmailDomainCounts(ctx context.Context, limit int) ([]DomainCount, error) {
+	return t.f.emailDomainCounts(ctx, t.tx, limit)
+}
+
+// emailDomainCounts groups users by the part of their email after the last @
+func (f *Frontend) emailDomainCounts(ctx context.Context, q querier, limit int) ([]DomainCount, error) {
+	limit = normalizeLimit(limit)
+
+	s := f.schema
+	domain := "LOWER(" + f.config.emailDomain(s.EmailColumn) + ")"
+	// Grouping by the expression rather than its alias keeps PostgreSQL from
+	// resolving the alias to a user column of the same name
+	query := fmt.Sprintf(`SELECT %s AS domain, COUNT(*) AS user_count FROM %s%s
+	          GROUP BY %s ORDER BY user_count DESC, domain LIMIT $1`,
+		domain, s.Table, f.where(), domain)
+
+	rows, err := f.query(ctx, q, query, limit)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer rows.Close()
+
+	counts := make([]DomainCount, 0)
+	for rows.Next() {
+		var c DomainCount
+		if err := rows.Scan(&c.Domain, &c.Count); err != nil {
+			return nil, databaseError(err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+
+	countRows(ctx, int64(len(counts)))
+	return counts, nil
+}
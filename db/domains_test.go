@@ -0,0 +1,21 @@
+package db
+
+import "testing"
+
+func TestEmailDomainTakesTextAfterLastAt(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		want   string
+	}{
+		{DriverPostgres, `SUBSTRING(email FROM '[^@]*$')`},
+		{DriverMySQL, `SUBSTRING_INDEX(email, '@', -1)`},
+		{DriverSQLite, `SUBSTR(email, LENGTH(RTRIM(email, REPLACE(email, '@', ''))) + 1)`},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		if got := config.emailDomain("email"); got != tt.want {
+			t.Errorf("%s: emailDomain = %s, want %s", tt.driver, got, tt.want)
+		}
+	}
+}
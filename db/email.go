@@ -0,0 +1,196 @@
+package db
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// EmailValidation selects how email addresses are validated
+type EmailValidation int
+
+const (
+	// EmailValidationPattern accepts the conservative ASCII pattern used by
+	// earlier releases. It rejects some valid addresses, such as quoted local
+	// parts and internationalized domains.
+	EmailValidationPattern EmailValidation = iota
+	// EmailValidationRFC5322 parses addresses with net/mail, accepting quoted
+	// local parts and UTF-8 (RFC 6532) addresses. Display names, angle
+	// brackets, surrounding whitespace, comments, needless quoting and IP
+	// domain literals are still rejected, as are addresses over 254 bytes or
+	// with a local part over 64 bytes.
+	EmailValidationRFC5322
+)
+
+// validateEmail validates email using the configured mode
+func (f *Frontend) validateEmail(email string) error {
+	_, err := f.parseEmail(email)
+	return err
+}
+
+// parseEmail validates email using the configured mode and returns the
+// domain of the address it parsed
+func (f *Frontend) parseEmail(email string) (domain string, err error) {
+	if f.config.EmailValidation == EmailValidationRFC5322 {
+		return parseEmailRFC5322(email)
+	}
+	if err := validateEmailPattern(email); err != nil {
+		return "", err
+	}
+	return email[strings.LastIndex(email, "@")+1:], nil
+}
+
+// validateNewEmail validates an email that is about to be written, applying
+// the domain lists and Config.EmailValidators on top of validateEmail
+func (f *Frontend) validateNewEmail(email string) error {
+	domain, err := f.parseEmail(email)
+	if err != nil {
+		return err
+	}
+	if err := f.checkEmailDomain(domain); err != nil {
+		return err
+	}
+	return runValidators(f.config.EmailValidators, "email", email)
+}
+
+// checkEmailDomain enforces Config.AllowedEmailDomains and
+// Config.BlockedEmailDomains on the domain of a parsed address. The error
+// does not say which list rejected the address, so callers cannot probe the
+// configured policy.
+func (f *Frontend) checkEmailDomain(domain string) error {
+	allowed, blocked := f.config.AllowedEmailDomains, f.config.BlockedEmailDomains
+	if len(allowed) == 0 && len(blocked) == 0 {
+		return nil
+	}
+
+	domain = strings.ToLower(domain)
+	if matchEmailDomain(blocked, domain) || len(allowed) > 0 && !matchEmailDomain(allowed, domain) {
+		return invalidField("email", CodeNotAllowed, "email domain is not allowed")
+	}
+	return nil
+}
+
+// matchEmailDomain reports whether a lowercased domain matches any entry,
+// where "*.example.com" matches subdomains of example.com only
+func matchEmailDomain(entries []string, domain string) bool {
+	for _, entry := range entries {
+		entry = strings.ToLower(entry)
+		if parent, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(domain, "."+parent) {
+				return true
+			}
+		} else if domain == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEmailDomainLists checks that every allow and block entry is a
+// domain, optionally prefixed with "*."
+func validateEmailDomainLists(config *Config) error {
+	lists := []struct {
+		field   string
+		entries []string
+	}{
+		{"AllowedEmailDomains", config.AllowedEmailDomains},
+		{"BlockedEmailDomains", config.BlockedEmailDomains},
+	}
+	for _, list := range lists {
+		for _, entry := range list.entries {
+			domain := strings.TrimPrefix(entry, "*.")
+			if !validEmailDomain(domain) || strings.ContainsAny(domain, "@* ") {
+				return invalidField(list.field, CodeInvalidFormat, fmt.Sprintf("invalid email domain entry %q", entry))
+			}
+		}
+	}
+	return nil
+}
+
+// RFC 5321 limits on the size of an address, in bytes
+const (
+	maxEmailLength      = 254
+	maxEmailLocalLength = 64
+)
+
+// validateEmailRFC5322 validates a bare addr-spec with net/mail
+func validateEmailRFC5322(email string) error {
+	_, err := parseEmailRFC5322(email)
+	return err
+}
+
+// parseEmailRFC5322 is validateEmailRFC5322 returning the parsed domain
+func parseEmailRFC5322(email string) (domain string, err error) {
+	if email == "" {
+		return "", invalidField("email", CodeRequired, "email is required")
+	}
+	if len(email) > maxEmailLength {
+		return "", invalidField("email", CodeTooLong, "email too long")
+	}
+	// A quoted local part may itself contain @, so the domain starts after
+	// the last one
+	if strings.LastIndex(email, "@") > maxEmailLocalLength {
+		return "", invalidField("email", CodeTooLong, "email local part too long")
+	}
+
+	// ParseAddress also accepts "Name <addr>" forms and drops surrounding
+	// whitespace and comments; only a bare address, written exactly as it
+	// formats, is acceptable for storage
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || strings.ContainsAny(email, "<>") || addr.String() != "<"+email+">" {
+		return "", invalidField("email", CodeInvalidFormat, "invalid email format")
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 1 || !validEmailDomain(addr.Address[at+1:]) {
+		return "", invalidField("email", CodeInvalidFormat, "invalid email format")
+	}
+	return addr.Address[at+1:], nil
+}
+
+// validEmailDomain checks that every label of a domain is non-empty and does
+// not start or end with a hyphen. Domain literals such as [192.0.2.1] are
+// rejected.
+func validEmailDomain(domain string) bool {
+	if domain == "" || strings.HasPrefix(domain, "[") {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// EmailNormalization selects which parts of an email address are lowercased
+// before it is stored or compared
+type EmailNormalization int
+
+const (
+	// EmailNormalizeNone stores and compares emails exactly as given
+	EmailNormalizeNone EmailNormalization = iota
+	// EmailNormalizeDomain lowercases the domain only. Domains are
+	// case-insensitive, so this never merges distinct mailboxes.
+	EmailNormalizeDomain
+	// EmailNormalizeAll lowercases the whole address. Local parts are
+	// case-sensitive per RFC 5321 but treated case-insensitively by nearly
+	// every provider; enable this only if your mail system agrees.
+	EmailNormalizeAll
+)
+
+// normalizeEmail applies the configured case folding to a validated email
+func (f *Frontend) normalizeEmail(email string) string {
+	switch f.config.EmailNormalization {
+	case EmailNormalizeAll:
+		return strings.ToLower(email)
+	case EmailNormalizeDomain:
+		at := strings.LastIndex(email, "@")
+		if at < 0 {
+			return email
+		}
+		return email[:at+1] + strings.ToLower(email[at+1:])
+	default:
+		return email
+	}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateEmailRFC5322(t *testing.T) {
+	label := func(n int) string { return strings.Repeat("a", n) }
+	// 64 + 1 + 189 = 254 bytes
+	longest := label(64) + "@" + label(63) + "." + label(63) + "." + label(61)
+
+	tests := []struct {
+		name  string
+		email string
+		code  ValidationCode // empty when the address is valid
+	}{
+		{"plain", "alice@example.com", ""},
+		{"UTF-8 domain", "alice@bücher.example", ""},
+		{"UTF-8 address", "用户@例子.广告", ""},
+		{"punycode domain", "alice@xn--bcher-kva.example", ""},
+		{"quoted local part", `"alice smith"@example.com`, ""},
+		{"quoted local part with @", `"alice@home"@example.com`, ""},
+
+		{"display name", "Bob <b@x.io>", CodeInvalidFormat},
+		{"quoted display name", `"Bob" <b@x.io>`, CodeInvalidFormat},
+		{"angle brackets", "<b@x.io>", CodeInvalidFormat},
+		{"IPv4 literal", "alice@[192.0.2.1]", CodeInvalidFormat},
+		{"IPv6 literal", "alice@[IPv6:2001:db8::1]", CodeInvalidFormat},
+		{"missing domain", "alice@", CodeInvalidFormat},
+		{"empty label", "alice@example..com", CodeInvalidFormat},
+		{"hyphen label", "alice@-example.com", CodeInvalidFormat},
+		{"unquoted space", "alice smith@example.com", CodeInvalidFormat},
+		{"trailing space", "alice@example.com ", CodeInvalidFormat},
+		{"leading space", " alice@example.com", CodeInvalidFormat},
+		{"trailing tab", "alice@example.com\t", CodeInvalidFormat},
+		{"trailing comment", "alice@example.com (home)", CodeInvalidFormat},
+		{"needless quotes", `"alice"@example.com`, CodeInvalidFormat},
+
+		{"empty", "", CodeRequired},
+		{"254 bytes", longest, ""},
+		{"255 bytes", longest + "a", CodeTooLong},
+		{"64-byte local part", label(64) + "@example.com", ""},
+		{"65-byte local part", label(65) + "@example.com", CodeTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEmailRFC5322(tt.email)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("validateEmailRFC5322(%q) = %v, want nil", tt.email, err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("validateEmailRFC5322(%q) = %v, want a ValidationError", tt.email, err)
+			}
+			if verr.Field != "email" || verr.Code != tt.code {
+				t.Errorf("validateEmailRFC5322(%q) = %s/%s, want email/%s", tt.email, verr.Field, verr.Code, tt.code)
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("validateEmailRFC5322(%q) does not match ErrInvalidInput", tt.email)
+			}
+		})
+	}
+}
+
+func TestEmailDomainListsUseParsedDomain(t *testing.T) {
+	attempts := []string{
+		"a@evil.com ",
+		" a@evil.com",
+		"a@evil.com\t",
+		"a@evil.com (work)",
+		"a@EVIL.com",
+		`"a@good.com"@evil.com`,
+	}
+	for _, mode := range []EmailValidation{EmailValidationPattern, EmailValidationRFC5322} {
+		db, _ := newFakeDB(t)
+		config := DefaultConfig()
+		config.EmailValidation = mode
+		config.BlockedEmailDomains = []string{"evil.com"}
+		f, err := NewFrontendWithDB(db, config)
+		if err != nil {
+			t.Fatalf("NewFrontendWithDB: %v", err)
+		}
+
+		for _, email := range attempts {
+			if err := f.validateNewEmail(email); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("mode %d: validateNewEmail(%q) = %v, want ErrInvalidInput", mode, email, err)
+			}
+		}
+		if err := f.validateNewEmail("a@good.com"); err != nil {
+			t.Errorf("mode %d: validateNewEmail(a@good.com) = %v, want nil", mode, err)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SQLSTATE codes inspected by this package
+const (
+	sqlStateUniqueViolation      = "23505"
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateLockNotAvailable     = "55P03"
+	sqlStateTooManyConnections   = "53300"
+	sqlStateAdminShutdown        = "57P01"
+	sqlStateCannotConnectNow     = "57P03"
+
+	// Class 08 covers every connection exception
+	sqlStateClassConnection = "08"
+)
+
+// sqlStateError is implemented by driver errors that expose a SQLSTATE code,
+// including *pq.Error (github.com/lib/pq) and *pgconn.PgError (pgx). Matching
+// on the interface keeps the package free of a hard driver dependency.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// mysqlStates maps MySQL server error numbers onto the PostgreSQL SQLSTATE
+// codes this package classifies by. MySQL's own SQLSTATE is too coarse to
+// use: a duplicate key reports 23000, shared by every integrity violation.
+var mysqlStates = map[uint16]string{
+	1062: sqlStateUniqueViolation,    // ER_DUP_ENTRY
+	1586: sqlStateUniqueViolation,    // ER_DUP_ENTRY_WITH_KEY_NAME
+	1213: sqlStateDeadlockDetected,   // ER_LOCK_DEADLOCK
+	1205: sqlStateLockNotAvailable,   // ER_LOCK_WAIT_TIMEOUT
+	1040: sqlStateTooManyConnections, // ER_CON_COUNT_ERROR
+	1203: sqlStateTooManyConnections, // ER_TOO_MANY_USER_CONNECTIONS
+	1053: sqlStateAdminShutdown,      // ER_SERVER_SHUTDOWN
+}
+
+// sqlState returns the SQLSTATE code carried by err, or "" if none is
+// available. Drivers without SQLSTATE methods are mapped onto the equivalent
+// PostgreSQL code, MySQL errors by number and SQLite errors by message, so
+// every driver is classified the same way.
+func sqlState(err error) string {
+	if err == nil {
+		return ""
+	}
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	if number, ok := mysqlErrorNumber(err); ok {
+		return mysqlStates[number]
+	}
+	return sqliteState(err)
+}
+
+// mysqlErrorNumber returns the server error number of a go-sql-driver/mysql
+// *MySQLError in err's chain. The driver exposes it as a Number field rather
+// than a method, so it is read by reflection, which like sqlStateError keeps
+// the package free of a driver dependency.
+func mysqlErrorNumber(err error) (uint16, bool) {
+	for err != nil {
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, inner := range multi.Unwrap() {
+				if number, ok := mysqlErrorNumber(inner); ok {
+					return number, true
+				}
+			}
+			return 0, false
+		}
+
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct &&
+			v.Elem().Type().Name() == "MySQLError" {
+			if number := v.Elem().FieldByName("Number"); number.IsValid() && number.Kind() == reflect.Uint16 {
+				return uint16(number.Uint()), true
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return 0, false
+}
+
+// sqliteState classifies SQLite errors by message. SQLite reports no
+// SQLSTATE, but the text comes from the library itself and so is the same in
+// github.com/mattn/go-sqlite3 and modernc.org/sqlite.
+func sqliteState(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return sqlStateUniqueViolation
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		// SQLITE_BUSY and SQLITE_LOCKED: the busy timeout ran out
+		return sqlStateLockNotAvailable
+	}
+	return ""
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation:
+// SQLSTATE 23505 on PostgreSQL, error 1062 on MySQL or a UNIQUE constraint
+// failure on SQLite
+func isUniqueViolation(err error) bool {
+	return sqlState(err) == sqlStateUniqueViolation
+}
+
+// isTxConflict reports whether err is a transient transaction conflict that
+// succeeds when the whole transaction is run again: a serialization failure,
+// a deadlock or a lock timeout. MySQL reports deadlocks as error 1213 and
+// lock wait timeouts as 1205, which sqlState maps onto these codes.
+func isTxConflict(err error) bool {
+	switch sqlState(err) {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected, sqlStateLockNotAvailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether err is transient, so that repeating the
+// operation, after a backoff, may succeed. It unwraps errors returned by this
+// package and classifies them as follows.
+//
+// Retryable: ErrTimeout, ErrConnectionFailed, ErrPoolExhausted and
+// ErrRateLimited, and database errors with SQLSTATE 40001 (serialization
+// failure), 40P01 (deadlock), 55P03 (lock not available), 53300 (too many
+// connections), 57P01 and 57P03 (server shutting down or starting up) or any
+// class 08 connection exception. MySQL errors 1213 (deadlock), 1205 (lock
+// wait timeout), 1040 and 1203 (too many connections) and 1053 (server
+// shutdown), and SQLite's "database is locked", map onto those codes. A
+// serialization failure or deadlock inside a transaction means the whole
+// transaction must be re-run, not the statement.
+//
+// Permanent: everything else, including ErrInvalidInput, ErrNotFound,
+// ErrDuplicate and other constraint violations, ErrVersionConflict,
+// ErrUnsupported, ErrShuttingDown and cancellation. A query cut off by the
+// caller's own deadline also reports ErrTimeout; check ctx.Err() before
+// retrying with the same context.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, transient := range []error{ErrTimeout, ErrConnectionFailed, ErrPoolExhausted, ErrRateLimited} {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+
+	state := sqlState(err)
+	switch state {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected, sqlStateLockNotAvailable,
+		sqlStateTooManyConnections, sqlStateAdminShutdown, sqlStateCannotConnectNow:
+		return true
+	}
+	return strings.HasPrefix(state, sqlStateClassConnection)
+}
+
+// duplicateError returns the error reported for unique constraint violations.
+// It matches both ErrDuplicate and, for existing callers, ErrInvalidInput.
+func duplicateError() error {
+	return fmt.Errorf("%w: %w", ErrInvalidInput, ErrDuplicate)
+}
+
+// NotFoundError identifies the missing record. It matches ErrNotFound with
+// errors.Is. Key is built by this package from non-sensitive identifiers such
+// as numeric IDs, never from usernames, emails or other caller input.
+type NotFoundError struct {
+	Entity string // e.g. "user"
+	Key    string // e.g. "id=42"
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrNotFound, e.Entity, e.Key)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ValidationCode is a stable, machine-readable reason for a ValidationError
+type ValidationCode string
+
+// Validation codes reported by this package
+const (
+	CodeRequired          ValidationCode = "required"
+	CodeInvalidLength     ValidationCode = "invalid_length"
+	CodeTooLong           ValidationCode = "too_long"
+	CodeInvalidFormat     ValidationCode = "invalid_format"
+	CodeInvalidCharacters ValidationCode = "invalid_characters"
+	CodeNotAllowed        ValidationCode = "not_allowed"
+	CodeRejected          ValidationCode = "rejected" // by a Config validator hook
+	CodeOutOfRange        ValidationCode = "out_of_range"
+	CodeUnsupported       ValidationCode = "unsupported"
+)
+
+// ValidationError identifies the input that failed validation, so an API
+// layer can report it per field. It matches ErrInvalidInput with errors.Is.
+// Field is "username", "email", "password" or "metadata" for user input,
+// "Isolation" for transaction options, or the Config field name for
+// configuration errors, qualified for nested fields as in "Schema.Table".
+type ValidationError struct {
+	Field   string
+	Code    ValidationCode
+	Message string // human-readable, e.g. "email too long"
+	Err     error  // the hook's error for CodeRejected
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidInput, e.Message)
+}
+
+// Is reports whether target is ErrInvalidInput
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// Unwrap returns the error a validator hook rejected the value with
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// invalidField returns a ValidationError for field
+func invalidField(field string, code ValidationCode, message string) error {
+	return &ValidationError{Field: field, Code: code, Message: message}
+}
+
+// userNotFound returns the error for a missing user ID
+func userNotFound(userID int64) error {
+	return &NotFoundError{Entity: "user", Key: "id=" + strconv.FormatInt(userID, 10)}
+}
+
+// sqlError is a sanitized database error that keeps the SQLSTATE code, as
+// normalized by sqlState, so retry logic and callers can still classify it
+// after the message has been scrubbed
+type sqlError struct {
+	err   error // ErrDatabaseError wrapping the sanitized message
+	state string
+}
+
+func (e *sqlError) Error() string    { return e.err.Error() }
+func (e *sqlError) Unwrap() error    { return e.err }
+func (e *sqlError) SQLState() string { return e.state }
+
+// databaseError wraps a driver error as ErrDatabaseError with a sanitized
+// message, preserving its SQLSTATE code when it has one. Timeouts and broken
+// connections also match ErrTimeout and ErrConnectionFailed, so IsRetryable
+// can classify them.
+func databaseError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrDatabaseError, ErrTimeout)
+	case errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr):
+		return fmt.Errorf("%w: %w", ErrDatabaseError, ErrConnectionFailed)
+	}
+
+	wrapped := fmt.Errorf("%w: %v", ErrDatabaseError, sanitizeError(err))
+	if state := sqlState(err); state != "" {
+		return &sqlError{err: wrapped, state: state}
+	}
+	return wrapped
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// pqError stands in for *pq.Error, which exposes its code as a method
+type pqError struct{ code string }
+
+func (e *pqError) Error() string    { return "pq: error " + e.code }
+func (e *pqError) SQLState() string { return e.code }
+
+// MySQLError mirrors the shape of go-sql-driver/mysql's *MySQLError, whose
+// number and SQLSTATE are fields, not methods
+type MySQLError struct {
+	Number   uint16
+	SQLState [5]byte
+	Message  string
+}
+
+func (e *MySQLError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+// sqliteError stands in for the SQLite drivers' errors, which carry only the
+// library's message
+type sqliteError struct{ msg string }
+
+func (e *sqliteError) Error() string { return e.msg }
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"postgres", &pqError{"23505"}, true},
+		{"postgres other", &pqError{"23503"}, false},
+		{"mysql", &MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'username'"}, true},
+		{"mysql with key name", &MySQLError{Number: 1586, Message: "Duplicate entry"}, true},
+		{"mysql other", &MySQLError{Number: 1048, Message: "Column 'email' cannot be null"}, false},
+		{"sqlite", &sqliteError{"UNIQUE constraint failed: users.email"}, true},
+		{"sqlite other", &sqliteError{"NOT NULL constraint failed: users.email"}, false},
+		{"wrapped", fmt.Errorf("insert: %w", &MySQLError{Number: 1062}), true},
+		{"joined", errors.Join(errors.New("first"), &MySQLError{Number: 1062}), true},
+		{"sanitized", databaseError(&MySQLError{Number: 1062, Message: "Duplicate entry"}), true},
+		{"plain", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("%s: isUniqueViolation(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCreateUserReportsDuplicatePerDriver(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		err    error
+	}{
+		{DriverPostgres, &pqError{"23505"}},
+		{DriverMySQL, &MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'username'"}},
+		{DriverSQLite, &sqliteError{"UNIQUE constraint failed: users.username"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.driver), func(t *testing.T) {
+			db, store := newFakeDB(t)
+			store.fail = func(string) error { return tt.err }
+			config := DefaultConfig()
+			config.Driver = tt.driver
+			f, err := NewFrontendWithDB(db, config)
+			if err != nil {
+				t.Fatalf("NewFrontendWithDB: %v", err)
+			}
+
+			_, err = f.CreateUser(context.Background(), "alice", "alice@example.com")
+			if !errors.Is(err, ErrDuplicate) {
+				t.Errorf("CreateUser = %v, want ErrDuplicate", err)
+			}
+		})
+	}
+}
+
+func TestIsTxConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"postgres serialization failure", &pqError{"40001"}, true},
+		{"postgres deadlock", &pqError{"40P01"}, true},
+		{"postgres lock timeout", &pqError{"55P03"}, true},
+		{"postgres unique violation", &pqError{"23505"}, false},
+		{"mysql deadlock", &MySQLError{Number: 1213, SQLState: [5]byte{'4', '0', '0', '0', '1'}}, true},
+		{"mysql lock wait timeout", &MySQLError{Number: 1205}, true},
+		{"mysql duplicate", &MySQLError{Number: 1062}, false},
+		{"sanitized mysql deadlock", databaseError(&MySQLError{Number: 1213}), true},
+		{"plain", errors.New("deadlock"), false},
+	}
+	for _, tt := range tests {
+		if got := isTxConflict(tt.err); got != tt.want {
+			t.Errorf("%s: isTxConflict(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTransactionRetriesMySQLDeadlock(t *testing.T) {
+	db, _ := newFakeDB(t)
+	config := DefaultConfig()
+	config.Driver = DriverMySQL
+	config.TxMaxRetries = 2
+	config.TxRetryBackoff = time.Microsecond
+	config.Logger = log.New(io.Discard, "", 0)
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	attempts := 0
+	err = f.ExecuteInTransaction(context.Background(), func(*Tx) error {
+		attempts++
+		if attempts == 1 {
+			return databaseError(&MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("ExecuteInTransaction = %v after %d attempts, want nil after 2", err, attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", ErrTimeout, true},
+		{"invalid input", ErrInvalidInput, false},
+		{"postgres too many connections", &pqError{"53300"}, true},
+		{"postgres connection exception", &pqError{"08006"}, true},
+		{"postgres unique violation", &pqError{"23505"}, false},
+		{"mysql deadlock", &MySQLError{Number: 1213}, true},
+		{"mysql lock wait timeout", &MySQLError{Number: 1205}, true},
+		{"mysql too many connections", &MySQLError{Number: 1040}, true},
+		{"mysql too many user connections", &MySQLError{Number: 1203}, true},
+		{"mysql server shutdown", &MySQLError{Number: 1053}, true},
+		{"mysql duplicate", &MySQLError{Number: 1062}, false},
+		{"sanitized mysql too many connections", databaseError(&MySQLError{Number: 1040}), true},
+		{"sqlite busy", &sqliteError{"database is locked"}, true},
+		{"sqlite constraint", &sqliteError{"UNIQUE constraint failed: users.email"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
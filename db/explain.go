@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Statement is a query captured by DryRun instead of being sent. Query is the
+// final text for the configured driver; argument values are redacted to their
+// Go types, so a Statement is safe to log.
+type Statement struct {
+	Op    string   `json:"op"`
+	Query string   `json:"query"`
+	Args  []string `json:"args"`
+}
+
+// errDryRun stops an operation at the statement DryRun captured
+var errDryRun = errors.New("dry run")
+
+// dryRunKey is the context key holding the active dryRun
+type dryRunKey struct{}
+
+// dryRun records the first statement an operation would send
+type dryRun struct {
+	stmt *Statement
+	args []any   // unredacted, for Explain only
+	q    querier // where the statement would have run
+	done context.Context
+}
+
+// DryRun runs fn, which should call one Frontend method with the context it
+// is given, and returns the first statement that method would send to the
+// database, without sending it. Validation still runs, so invalid input is
+// reported as usual. Operations that open a transaction, such as audited
+// writes, still begin and roll back an empty one.
+//
+//	stmt, err := frontend.DryRun(ctx, func(ctx context.Context) error {
+//		_, err := frontend.SearchUsers(ctx, "alice", 10)
+//		return err
+//	})
+func (f *Frontend) DryRun(ctx context.Context, fn func(ctx context.Context) error) (*Statement, error) {
+	run, err := f.capture(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	return run.stmt, nil
+}
+
+// Explain is DryRun followed by asking the database for its plan of the
+// captured statement, returned as JSON. The plan is produced by EXPLAIN
+// without ANALYZE, so the statement itself is still not executed. PostgreSQL
+// and MySQL are supported; SQLite returns ErrUnsupported.
+func (f *Frontend) Explain(ctx context.Context, fn func(ctx context.Context) error) (string, error) {
+	var prefix string
+	switch f.config.driver() {
+	case DriverPostgres:
+		prefix = "EXPLAIN (FORMAT JSON) "
+	case DriverMySQL:
+		prefix = "EXPLAIN FORMAT=JSON "
+	default:
+		return "", fmt.Errorf("%w: explain requires postgres or mysql", ErrUnsupported)
+	}
+
+	run, err := f.capture(ctx, fn)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := f.withQueryTimeout(ctx)
+	defer cancel()
+
+	// An audited write captured its statement in a transaction that has
+	// since rolled back; plan it on the primary instead
+	q := run.q
+	if _, ok := q.(*sql.Tx); ok {
+		q = f.primary()
+	}
+
+	// The captured text is already rebound for the driver, so it is sent
+	// as is rather than through queryRow
+	var plan string
+	if err := q.QueryRowContext(ctx, prefix+run.stmt.Query, run.args...).Scan(&plan); err != nil {
+		return "", databaseError(err)
+	}
+	return plan, nil
+}
+
+// capture runs fn in dry-run mode and returns the recorded statement, or the
+// error fn failed with before reaching the database
+func (f *Frontend) capture(ctx context.Context, fn func(ctx context.Context) error) (*dryRun, error) {
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	run := &dryRun{done: done}
+	err := fn(context.WithValue(ctx, dryRunKey{}, run))
+	if run.stmt != nil {
+		return run, nil
+	}
+	if err == nil {
+		return nil, fmt.Errorf("%w: operation sent no statement", ErrInvalidInput)
+	}
+	return nil, err
+}
+
+// recordDryRun captures query when ctx is in dry-run mode, reporting whether
+// it did. Only the first statement is kept; the operation fails once it
+// tries to use the result.
+func (f *Frontend) recordDryRun(ctx context.Context, q querier, query string, args []any) (*dryRun, bool) {
+	run, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	if !ok {
+		return nil, false
+	}
+	if run.stmt == nil {
+		redacted := make([]string, len(args))
+		for i, arg := range args {
+			redacted[i] = fmt.Sprintf("%T", arg)
+		}
+		run.stmt = &Statement{Op: opFromContext(ctx), Query: query, Args: redacted}
+		run.args = args
+		run.q = q
+	}
+	return run, true
+}
@@ -0,0 +1,422 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore is a minimal in-memory database behind a database/sql driver. It
+// understands the statement shapes this package builds for the users table
+// with the postgres dialect ($N placeholders and RETURNING): INSERT, SELECT,
+// UPDATE, DELETE and COPY FROM STDIN.
+//
+// Only conditions of the form "col = $N", "col <= $N" on timestamps and
+// "col IS NULL" are evaluated; anything else, such as LIKE, is treated as
+// true. Tests therefore decide which rows match through equality conditions
+// such as the ID and tenant columns, which is exactly what isolation depends
+// on. A NULL argument never equals anything, as in SQL.
+type fakeStore struct {
+	mu     sync.Mutex
+	rows   []map[string]driver.Value
+	nextID int64
+	// queries logs every statement in the order it ran
+	queries []string
+	// prepares counts driver-level Prepare calls, each of which a real
+	// server would parse and plan
+	prepares int
+	// begins counts transactions opened on the store
+	begins int
+	// fail, when set, is consulted before each statement runs; a non-nil
+	// result is returned as the driver's error
+	fail func(query string) error
+}
+
+var _ driver.Connector = (*fakeStore)(nil)
+
+// newFakeDB returns a pool backed by a new, empty fakeStore
+func newFakeDB(t testing.TB) (*sql.DB, *fakeStore) {
+	t.Helper()
+	store := &fakeStore{nextID: 1}
+	db := sql.OpenDB(store)
+	t.Cleanup(func() { db.Close() })
+	return db, store
+}
+
+// seed inserts a row directly and returns its ID
+func (s *fakeStore) seed(values map[string]driver.Value) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row := map[string]driver.Value{"created_at": time.Now()}
+	for k, v := range values {
+		row[k] = v
+	}
+	row["id"] = s.nextID
+	s.nextID++
+	s.rows = append(s.rows, row)
+	return row["id"].(int64)
+}
+
+// find returns a copy of the row with id, or nil
+func (s *fakeStore) find(id int64) map[string]driver.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.rows {
+		if row["id"] == id {
+			return cloneRow(row)
+		}
+	}
+	return nil
+}
+
+// all returns copies of every row in insertion order
+func (s *fakeStore) all() []map[string]driver.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := make([]map[string]driver.Value, len(s.rows))
+	for i, row := range s.rows {
+		rows[i] = cloneRow(row)
+	}
+	return rows
+}
+
+func (s *fakeStore) Connect(context.Context) (driver.Conn, error) { return &fakeConn{store: s}, nil }
+func (s *fakeStore) Driver() driver.Driver                        { return fakeDriver{} }
+
+// fakeDriver only exists to satisfy driver.Connector; pools are opened with
+// sql.OpenDB
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("fake driver: use sql.OpenDB")
+}
+
+type fakeConn struct {
+	store *fakeStore
+	// snapshot holds the rows at Begin, restored on Rollback
+	snapshot []map[string]driver.Value
+	inTx     bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.store.mu.Lock()
+	c.store.prepares++
+	c.store.mu.Unlock()
+	return &fakeStmt{conn: c, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.begins++
+	c.snapshot = make([]map[string]driver.Value, len(s.rows))
+	for i, row := range s.rows {
+		c.snapshot[i] = cloneRow(row)
+	}
+	c.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.inTx, c.snapshot = false, nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = c.snapshot
+	c.inTx, c.snapshot = false, nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+	// copied counts rows buffered by a COPY statement
+	copied int64
+}
+
+func (st *fakeStmt) Close() error  { return nil }
+func (st *fakeStmt) NumInput() int { return -1 }
+
+func (st *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s := st.conn.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, st.query)
+
+	if m := copyPattern.FindStringSubmatch(st.query); m != nil {
+		if len(args) == 0 {
+			return driver.RowsAffected(st.copied), nil
+		}
+		s.insertRow(splitList(m[1]), args)
+		st.copied++
+		return driver.RowsAffected(1), nil
+	}
+
+	_, rows, err := s.run(st.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+func (st *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s := st.conn.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, st.query)
+
+	columns, rows, err := s.run(st.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+var (
+	copyPattern   = regexp.MustCompile(`^COPY \S+ \((.*)\) FROM STDIN$`)
+	insertPattern = regexp.MustCompile(`^INSERT INTO \S+ \(([^)]*)\) VALUES (.*?)(?: RETURNING (.*))?$`)
+	selectPattern = regexp.MustCompile(`^SELECT (.*?) FROM \S+(.*)$`)
+	updatePattern = regexp.MustCompile(`^UPDATE \S+ SET (.*?)( WHERE .*)$`)
+	deletePattern = regexp.MustCompile(`^DELETE FROM \S+(.*)$`)
+
+	tuplePattern     = regexp.MustCompile(`\(([^()]*)\)`)
+	equalsPattern    = regexp.MustCompile(`(\w+) = \$(\d+)\b`)
+	atMostPattern    = regexp.MustCompile(`(\w+) <= \$(\d+)\b`)
+	isNullPattern    = regexp.MustCompile(`(\w+) IS NULL`)
+	limitPattern     = regexp.MustCompile(`LIMIT \$(\d+)`)
+	offsetPattern    = regexp.MustCompile(`OFFSET \$(\d+)`)
+	returningPattern = regexp.MustCompile(` RETURNING (.*)$`)
+	clauseEnd        = regexp.MustCompile(` (ORDER BY|LIMIT|OFFSET|FOR UPDATE|RETURNING)\b`)
+)
+
+// run executes a statement with the store locked and returns the selected or
+// returned columns and rows. For writes without RETURNING the rows are the
+// affected ones and only their count is used.
+func (s *fakeStore) run(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	if s.fail != nil {
+		if err := s.fail(query); err != nil {
+			return nil, nil, err
+		}
+	}
+	if m := insertPattern.FindStringSubmatch(query); m != nil {
+		columns := splitList(m[1])
+		var inserted []map[string]driver.Value
+		for _, tuple := range tuplePattern.FindAllStringSubmatch(m[2], -1) {
+			values := make([]driver.Value, 0, len(columns))
+			for _, placeholder := range splitList(tuple[1]) {
+				values = append(values, placeholderArg(placeholder, args))
+			}
+			inserted = append(inserted, s.insertRow(columns, values))
+		}
+		return project(m[3], inserted)
+	}
+
+	if m := selectPattern.FindStringSubmatch(query); m != nil {
+		matched := s.matching(m[2], args)
+		matched = page(m[2], args, matched)
+		if m[1] == "COUNT(*)" {
+			return []string{"count"}, [][]driver.Value{{int64(len(matched))}}, nil
+		}
+		return project(m[1], matched)
+	}
+
+	if m := updatePattern.FindStringSubmatch(query); m != nil {
+		matched := s.matching(m[2], args)
+		for _, assignment := range equalsPattern.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(assignment[2])
+			for _, row := range matched {
+				row[assignment[1]] = args[n-1]
+			}
+		}
+		return returning(query, matched)
+	}
+
+	if m := deletePattern.FindStringSubmatch(query); m != nil {
+		matched := s.matching(m[1], args)
+		kept := s.rows[:0]
+		for _, row := range s.rows {
+			if !containsRow(matched, row) {
+				kept = append(kept, row)
+			}
+		}
+		s.rows = kept
+		return returning(query, matched)
+	}
+
+	return nil, nil, fmt.Errorf("fake driver: unsupported statement: %s", query)
+}
+
+// insertRow appends a row with the next ID and returns it
+func (s *fakeStore) insertRow(columns []string, values []driver.Value) map[string]driver.Value {
+	row := map[string]driver.Value{"id": s.nextID}
+	s.nextID++
+	for i, column := range columns {
+		row[column] = values[i]
+	}
+	s.rows = append(s.rows, row)
+	return row
+}
+
+// matching returns the rows satisfying the WHERE clause in rest, the query
+// text after the table name
+func (s *fakeStore) matching(rest string, args []driver.Value) []map[string]driver.Value {
+	where := ""
+	if i := strings.Index(rest, " WHERE "); i >= 0 {
+		where = rest[i+len(" WHERE "):]
+		if loc := clauseEnd.FindStringIndex(where); loc != nil {
+			where = where[:loc[0]]
+		}
+	}
+
+	var matched []map[string]driver.Value
+	for _, row := range s.rows {
+		if rowMatches(row, where, args) {
+			matched = append(matched, row)
+		}
+	}
+	return matched
+}
+
+func rowMatches(row map[string]driver.Value, where string, args []driver.Value) bool {
+	for _, m := range equalsPattern.FindAllStringSubmatch(where, -1) {
+		n, _ := strconv.Atoi(m[2])
+		if !valuesEqual(row[m[1]], args[n-1]) {
+			return false
+		}
+	}
+	for _, m := range atMostPattern.FindAllStringSubmatch(where, -1) {
+		n, _ := strconv.Atoi(m[2])
+		a, ok := row[m[1]].(time.Time)
+		b, _ := args[n-1].(time.Time)
+		if !ok || a.After(b) {
+			return false
+		}
+	}
+	for _, m := range isNullPattern.FindAllStringSubmatch(where, -1) {
+		if row[m[1]] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// page applies LIMIT and OFFSET placeholders found in rest
+func page(rest string, args []driver.Value, rows []map[string]driver.Value) []map[string]driver.Value {
+	if m := offsetPattern.FindStringSubmatch(rest); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		offset := int(args[n-1].(int64))
+		rows = rows[min(offset, len(rows)):]
+	}
+	if m := limitPattern.FindStringSubmatch(rest); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		rows = rows[:min(int(args[n-1].(int64)), len(rows))]
+	}
+	return rows
+}
+
+// returning projects rows onto the RETURNING list of query, if any
+func returning(query string, rows []map[string]driver.Value) ([]string, [][]driver.Value, error) {
+	list := ""
+	if m := returningPattern.FindStringSubmatch(query); m != nil {
+		list = m[1]
+	}
+	return project(list, rows)
+}
+
+// project returns the listed columns of rows; an empty list returns the rows
+// with no columns, for their count
+func project(list string, rows []map[string]driver.Value) ([]string, [][]driver.Value, error) {
+	var columns []string
+	if list != "" {
+		columns = splitList(list)
+	}
+	values := make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		values[i] = make([]driver.Value, len(columns))
+		for j, column := range columns {
+			values[i][j] = row[column]
+		}
+	}
+	return columns, values, nil
+}
+
+func placeholderArg(placeholder string, args []driver.Value) driver.Value {
+	n, err := strconv.Atoi(strings.TrimPrefix(placeholder, "$"))
+	if err != nil || n < 1 || n > len(args) {
+		panic("fake driver: bad placeholder " + placeholder)
+	}
+	return args[n-1]
+}
+
+func splitList(list string) []string {
+	parts := strings.Split(list, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+func valuesEqual(a, b driver.Value) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if ab, ok := a.([]byte); ok {
+		bb, ok := b.([]byte)
+		return ok && bytes.Equal(ab, bb)
+	}
+	if _, ok := b.([]byte); ok {
+		return false
+	}
+	return a == b
+}
+
+func containsRow(rows []map[string]driver.Value, row map[string]driver.Value) bool {
+	for _, r := range rows {
+		if r["id"] == row["id"] {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneRow(row map[string]driver.Value) map[string]driver.Value {
+	clone := make(map[string]driver.Value, len(row))
+	for k, v := range row {
+		clone[k] = v
+	}
+	return clone
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
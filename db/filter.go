@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserFilter selects users by optional criteria for FilterUsers. Each non-nil
+// field adds one condition, and conditions are joined with AND; the zero
+// value matches every user. Values are always bound as parameters.
+type UserFilter struct {
+	// UsernameContains matches usernames containing the value literally;
+	// LIKE wildcards in it are escaped
+	UsernameContains *string
+	// EmailDomain matches emails at exactly this domain, case-insensitively;
+	// subdomains do not match
+	EmailDomain *string
+	// CreatedAfter matches users created strictly after this time
+	CreatedAfter *time.Time
+}
+
+// FilterUsers returns a page of users matching filter, newest first
+func (f *Frontend) FilterUsers(ctx context.Context, filter UserFilter, limit, offset int) ([]*User, error) {
+	return instrumentResult(ctx, f, "FilterUsers", func(ctx context.Context) ([]*User, error) {
+		return f.filterUsers(ctx, f.reader(), filter, limit, offset)
+	})
+}
+
+// FilterUsers returns a page of users matching filter within the transaction
+func (t *Tx) FilterUsers(ctx context.Context, filter UserFilter, limit, offset int) ([]*User, error) {
+	return t.f.filterUsers(ctx, t.tx, filter, limit, offset)
+}
+
+// filterUsers runs the query built from filter
+func (f *Frontend) filterUsers(ctx context.Context, q querier, filter UserFilter, limit, offset int) ([]*User, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidInput)
+	}
+	conditions, args, err := f.filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := f.orderBy(UserSort{})
+	if err != nil {
+		return nil, err
+	}
+	limit = normalizeLimit(limit)
+
+	s := f.schema
+	query := fmt.Sprintf(`SELECT %s FROM %s%s
+	          ORDER BY %s LIMIT $%d OFFSET $%d`,
+		f.userColumns(), s.Table, f.where(conditions...), orderBy, len(args)+1, len(args)+2)
+
+	rows, err := f.query(ctx, q, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+
+	return f.collectUsers(ctx, rows)
+}
+
+// filterConditions validates filter and turns each set field into a
+// condition over schema identifiers with its value bound to the next
+// placeholder
+func (f *Frontend) filterConditions(filter UserFilter) ([]string, []any, error) {
+	s := f.schema
+	escape := f.config.likeEscape()
+	var conditions []string
+	var args []any
+	bind := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UsernameContains != nil {
+		term := *filter.UsernameContains
+		if err := validateSearchTerm(term, f.config.maxSearchTermLength()); err != nil {
+			return nil, nil, err
+		}
+		bind(s.UsernameColumn+" LIKE $%d"+escape, "%"+escapeLike(term)+"%")
+	}
+	if filter.EmailDomain != nil {
+		domain := strings.ToLower(*filter.EmailDomain)
+		if !validEmailDomain(domain) || strings.ContainsAny(domain, "@* ") {
+			return nil, nil, invalidField("EmailDomain", CodeInvalidFormat, "invalid email domain")
+		}
+		bind("LOWER("+s.EmailColumn+") LIKE $%d"+escape, "%@"+escapeLike(domain))
+	}
+	if filter.CreatedAfter != nil {
+		bind(s.CreatedAtColumn+" > $%d", *filter.CreatedAfter)
+	}
+	return conditions, args, nil
+}
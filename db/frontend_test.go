@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeErrorRedactsConnectionDetails(t *testing.T) {
+	// The shapes lib/pq reports when a connection attempt fails, with the
+	// key/value DSN echoed back
+	raw := errors.New(`pq: could not connect to server: dial tcp 10.0.0.5:5432: connect: connection refused ` +
+		`(host=db.internal.example.com port=5432 user=app_rw password=hunter2 dbname=accounts sslmode=require)`)
+	secrets := []string{"10.0.0.5", "5432", "db.internal.example.com", "app_rw", "hunter2", "accounts"}
+
+	sanitized := sanitizeError(raw).Error()
+	for _, secret := range secrets {
+		if strings.Contains(sanitized, secret) {
+			t.Errorf("sanitizeError kept %q: %s", secret, sanitized)
+		}
+	}
+	if !strings.Contains(sanitized, "connection refused") {
+		t.Errorf("sanitizeError dropped the cause: %s", sanitized)
+	}
+
+	err := databaseError(raw)
+	if !errors.Is(err, ErrDatabaseError) {
+		t.Errorf("databaseError(%v) does not match ErrDatabaseError", err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("databaseError kept %q: %s", secret, err)
+		}
+	}
+}
+
+func TestDatabaseErrorClassifiesDialFailure(t *testing.T) {
+	// Dial errors arrive as *net.OpError, which must not leak the address
+	raw := &net.OpError{
+		Op:   "dial",
+		Net:  "tcp",
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5432},
+		Err:  errors.New("connect: connection refused"),
+	}
+
+	err := databaseError(raw)
+	if !errors.Is(err, ErrConnectionFailed) || !errors.Is(err, ErrDatabaseError) {
+		t.Errorf("databaseError(dial) = %v, want ErrConnectionFailed and ErrDatabaseError", err)
+	}
+	if strings.Contains(err.Error(), "10.0.0.5") || strings.Contains(err.Error(), "5432") {
+		t.Errorf("databaseError kept the address: %s", err)
+	}
+}
+
+func TestConfigErrorsNameTheField(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(*Config)
+		field  string
+		code   ValidationCode
+	}{
+		{"table", func(c *Config) { c.Schema.Table = "users; DROP" }, "Schema.Table", CodeInvalidFormat},
+		{"column", func(c *Config) { c.Schema.EmailColumn = "e-mail" }, "Schema.EmailColumn", CodeInvalidFormat},
+		{"optional column", func(c *Config) { c.Schema.TenantColumn = "tenant id" }, "Schema.TenantColumn", CodeInvalidFormat},
+		{"search mode", func(c *Config) { c.SearchMode = SearchMode(99) }, "SearchMode", CodeUnsupported},
+		{"rate limits", func(c *Config) { c.RateLimits = &RateLimitConfig{Global: &RateLimit{Rate: 0, Burst: 1}} }, "RateLimits", CodeOutOfRange},
+		{"audit table", func(c *Config) { c.AuditTable = "audit log" }, "AuditTable", CodeInvalidFormat},
+		{"hash cost", func(c *Config) { c.PasswordHashCost = 99 }, "PasswordHashCost", CodeOutOfRange},
+		{"read replica", func(c *Config) { c.ReadReplica = &ReadConfig{} }, "ReadReplica", CodeUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := newFakeDB(t)
+			config := DefaultConfig()
+			tt.config(config)
+
+			_, err := NewFrontendWithDB(db, config)
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("NewFrontendWithDB = %v, want a ValidationError", err)
+			}
+			if ve.Field != tt.field || ve.Code != tt.code {
+				t.Errorf("ValidationError{Field: %q, Code: %q}, want {%q, %q}", ve.Field, ve.Code, tt.field, tt.code)
+			}
+		})
+	}
+}
+
+func TestReadReplicaErrorsNameTheField(t *testing.T) {
+	tests := []struct {
+		replica ReadConfig
+		driver  Driver
+		field   string
+		code    ValidationCode
+	}{
+		{ReadConfig{MaxLag: -1}, DriverPostgres, "ReadReplica.MaxLag", CodeOutOfRange},
+		{ReadConfig{LagCheckInterval: -1}, DriverPostgres, "ReadReplica.LagCheckInterval", CodeOutOfRange},
+		{ReadConfig{MaxLag: 1}, DriverMySQL, "ReadReplica.MaxLag", CodeUnsupported},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		config.ReadReplica = &tt.replica
+
+		var ve *ValidationError
+		if err := validateReadReplica(config); !errors.As(err, &ve) || ve.Field != tt.field || ve.Code != tt.code {
+			t.Errorf("validateReadReplica(%+v) = %v, want field %s with code %s", tt.replica, err, tt.field, tt.code)
+		}
+	}
+}
+
+func TestSSLErrorsNameTheField(t *testing.T) {
+	tests := []struct {
+		config func(*Config)
+		field  string
+		code   ValidationCode
+	}{
+		{func(c *Config) { c.SSLMode = "sometimes" }, "SSLMode", CodeUnsupported},
+		{func(c *Config) { c.SSLKey = "client.key" }, "SSLCert", CodeRequired},
+		{func(c *Config) { c.SSLCert = "client.crt" }, "SSLKey", CodeRequired},
+		{func(c *Config) { c.Driver = DriverMySQL; c.SSLRootCert = "ca.pem" }, "SSLRootCert", CodeUnsupported},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.config(config)
+
+		var ve *ValidationError
+		if err := validateSSL(config); !errors.As(err, &ve) || ve.Field != tt.field || ve.Code != tt.code {
+			t.Errorf("validateSSL = %v, want field %s with code %s", err, tt.field, tt.code)
+		}
+	}
+}
+
+func TestInputErrorsNameTheField(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		field string
+		code  ValidationCode
+	}{
+		{"short password", validatePassword("short"), "password", CodeInvalidLength},
+		{"metadata too large", validateMetadata([]byte(`{"a":1}`), 4), "metadata", CodeTooLong},
+		{"metadata not an object", validateMetadata([]byte(`[1]`), 64), "metadata", CodeInvalidFormat},
+		{"isolation", validateTxOptions(&sql.TxOptions{Isolation: sql.LevelLinearizable}), "Isolation", CodeUnsupported},
+	}
+	for _, tt := range tests {
+		var ve *ValidationError
+		if !errors.As(tt.err, &ve) || ve.Field != tt.field || ve.Code != tt.code {
+			t.Errorf("%s: %v, want field %s with code %s", tt.name, tt.err, tt.field, tt.code)
+		}
+	}
+}
+
+// dsnEchoDriver fails to parse every DSN with an error that quotes it, as
+// driver parse errors may
+type dsnEchoDriver struct{}
+
+func (d dsnEchoDriver) Open(dsn string) (driver.Conn, error) {
+	return nil, fmt.Errorf("invalid DSN %s", dsn)
+}
+
+// dsnEchoContextDriver also implements driver.DriverContext
+type dsnEchoContextDriver struct{ dsnEchoDriver }
+
+func (d dsnEchoContextDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	return nil, fmt.Errorf("cannot parse %s", dsn)
+}
+
+func TestProviderConnectorRedactsDSN(t *testing.T) {
+	for _, driverType := range []Driver{DriverPostgres, DriverMySQL} {
+		for _, drv := range []driver.Driver{dsnEchoDriver{}, dsnEchoContextDriver{}} {
+			config := DefaultConfig()
+			config.Driver = driverType
+			config.Host = "db.internal.example.com"
+			config.Database = "accounts"
+			config.Credentials = CredentialProviderFunc(func(context.Context) (string, string, error) {
+				return "app_rw", "hunter2", nil
+			})
+
+			c := &providerConnector{drv: drv, config: config}
+			_, err := c.Connect(context.Background())
+			if !errors.Is(err, ErrConnectionFailed) {
+				t.Errorf("%s %T: Connect = %v, want ErrConnectionFailed", driverType, drv, err)
+				continue
+			}
+			for _, secret := range []string{"hunter2", "app_rw", "db.internal.example.com"} {
+				if strings.Contains(err.Error(), secret) {
+					t.Errorf("%s %T: Connect kept %q: %s", driverType, drv, secret, err)
+				}
+			}
+		}
+	}
+}
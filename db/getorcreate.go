@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// GetOrCreateUser returns the user with email, creating it with username if
+// no such user exists. created reports whether a new row was inserted. Both
+// inputs are validated up front, as for CreateUser, even when the user
+// already exists.
+//
+// A concurrent call creating the same email is resolved by the unique index:
+// the losing insert fails with ErrDuplicate and the winner's row is read back
+// instead. ErrDuplicate is returned only when the email is still not found,
+// meaning username belongs to a different user. Lookups read the primary so
+// a just-created user is never missed.
+func (f *Frontend) GetOrCreateUser(ctx context.Context, username, email string) (user *User, created bool, err error) {
+	err = f.instrument(ctx, "GetOrCreateUser", func(ctx context.Context) error {
+		if err := f.validateGetOrCreate(username, email); err != nil {
+			return err
+		}
+
+		user, err = f.getUserByEmail(ctx, f.primary(), email)
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		user, err = auditWrite(ctx, f, "GetOrCreateUser", func(q querier) (*User, []int64, error) {
+			return createdUser(f.createUser(ctx, q, username, email))
+		})
+		if err == nil {
+			created = true
+			return nil
+		}
+		if !errors.Is(err, ErrDuplicate) {
+			return err
+		}
+
+		// Lost a race, or the username is taken by someone else
+		existing, lookupErr := f.getUserByEmail(ctx, f.primary(), email)
+		if lookupErr != nil {
+			if errors.Is(lookupErr, ErrNotFound) {
+				return err
+			}
+			return lookupErr
+		}
+		user = existing
+		return nil
+	})
+	return user, created, err
+}
+
+// GetOrCreateUser returns the user with email or creates it within the
+// transaction. Unlike Frontend.GetOrCreateUser it cannot recover from a
+// concurrent insert: PostgreSQL aborts the transaction on the unique
+// violation, so ErrDuplicate is returned and the caller should retry the
+// whole transaction.
+func (t *Tx) GetOrCreateUser(ctx context.Context, username, email string) (*User, bool, error) {
+	if err := t.f.validateGetOrCreate(username, email); err != nil {
+		return nil, false, err
+	}
+
+	user, err := t.f.getUserByEmail(ctx, t.tx, email)
+	if !errors.Is(err, ErrNotFound) {
+		return user, false, err
+	}
+	user, err = txAuditWrite(ctx, t, "GetOrCreateUser", func(q querier) (*User, []int64, error) {
+		return createdUser(t.f.createUser(ctx, q, username, email))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// validateGetOrCreate applies the CreateUser rules before any lookup
+func (f *Frontend) validateGetOrCreate(username, email string) error {
+	if err := f.validateNewUsername(username); err != nil {
+		return err
+	}
+	return f.validateNewEmail(email)
+}
@@ -1,3 +1,5 @@
 module github.com/kushmanmb-org/.github/db
 
 go 1.24.13
+
+require golang.org/x/crypto v0.48.0
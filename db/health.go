@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Health is a detailed health report for readiness endpoints. It carries no
+// hostnames or credentials, so it can be served as JSON directly.
+type Health struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	Primary   PoolHealth  `json:"primary"`
+	Replica   *PoolHealth `json:"replica,omitempty"` // nil without a read replica
+}
+
+// PoolHealth reports the checks and connection counts of one pool
+type PoolHealth struct {
+	Healthy     bool          `json:"healthy"`
+	PingLatency time.Duration `json:"ping_latency_ns"`
+	QueryOK     bool          `json:"query_ok"`
+	// Error is the sanitized failure message; empty when Healthy
+	Error   string `json:"error,omitempty"`
+	Open    int    `json:"open"`
+	InUse   int    `json:"in_use"`
+	Idle    int    `json:"idle"`
+	MaxOpen int    `json:"max_open"`
+	// WaitCount is the total number of times a caller waited for a connection
+	WaitCount int64 `json:"wait_count"`
+}
+
+// HealthReport runs the same checks as HealthCheck and returns the details
+// of each pool. The report is always returned, even when a check fails; the
+// error is then the one HealthCheck would return.
+func (f *Frontend) HealthReport(ctx context.Context) (*Health, error) {
+	ctx, cancel := withHealthTimeout(ctx)
+	defer cancel()
+
+	primary, replica := f.pools()
+	report := &Health{CheckedAt: time.Now()}
+
+	var err error
+	report.Primary, err = f.checkPool(ctx, primary)
+	if replica != nil {
+		health, replicaErr := f.checkPool(ctx, replica)
+		report.Replica = &health
+		if err == nil && replicaErr != nil {
+			err = fmt.Errorf("read replica: %w", replicaErr)
+		}
+	}
+	return report, err
+}
+
+// defaultHealthTimeout bounds health checks whose context has no deadline
+const defaultHealthTimeout = 5 * time.Second
+
+// withHealthTimeout applies defaultHealthTimeout unless ctx already has a
+// deadline, which is then used as is, whether shorter or longer
+func withHealthTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, defaultHealthTimeout)
+}
+
+// checkPool pings db, runs the health check query and collects its pool
+// statistics
+func (f *Frontend) checkPool(ctx context.Context, db *sql.DB) (PoolHealth, error) {
+	stats := db.Stats()
+	health := PoolHealth{
+		Open:      stats.OpenConnections,
+		InUse:     stats.InUse,
+		Idle:      stats.Idle,
+		MaxOpen:   stats.MaxOpenConnections,
+		WaitCount: stats.WaitCount,
+	}
+
+	start := time.Now()
+	err := db.PingContext(ctx)
+	health.PingLatency = time.Since(start)
+	if err != nil {
+		err = fmt.Errorf("%w: health check failed: %v", ErrConnectionFailed, sanitizeError(err))
+		health.Error = err.Error()
+		return health, err
+	}
+
+	if err := runHealthQuery(ctx, db, f.config.healthCheckQuery()); err != nil {
+		err = fmt.Errorf("%w: query check failed: %v", ErrDatabaseError, sanitizeError(err))
+		health.Error = err.Error()
+		return health, err
+	}
+
+	health.QueryOK = true
+	health.Healthy = true
+	return health, nil
+}
+
+// defaultHealthCheckQuery is used when Config.HealthCheckQuery is empty
+const defaultHealthCheckQuery = "SELECT 1"
+
+// healthCheckQuery returns the configured health check query or the default
+func (c *Config) healthCheckQuery() string {
+	if c.HealthCheckQuery == "" {
+		return defaultHealthCheckQuery
+	}
+	return c.HealthCheckQuery
+}
+
+// runHealthQuery runs query and drains its result, so the check fails on
+// errors raised while rows are produced as well as at execution
+func runHealthQuery(ctx context.Context, db *sql.DB, query string) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultIdempotencyKeyTTL is used when Config.IdempotencyKeyTTL is zero
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength matches the key column of the idempotency table
+const maxIdempotencyKeyLength = 255
+
+// errIdempotencyRace reports that a concurrent transaction claimed the same
+// key first; the request is answered by reading that transaction's user
+var errIdempotencyRace = errors.New("idempotency key claimed concurrently")
+
+// idempotencyKeyTTL returns the configured key lifetime or the default
+func (c *Config) idempotencyKeyTTL() time.Duration {
+	if c.IdempotencyKeyTTL == 0 {
+		return defaultIdempotencyKeyTTL
+	}
+	return c.IdempotencyKeyTTL
+}
+
+// CreateUserIdempotent is CreateUser guarded by a client-supplied key, such
+// as an Idempotency-Key request header, so a retried signup does not create
+// a second user. The first call with key creates the user and records the
+// key in Config.IdempotencyTable in the same transaction; later calls with
+// the same key within Config.IdempotencyKeyTTL return that user instead of
+// inserting, whatever username and email they pass. An expired key is
+// forgotten and creates a new user.
+//
+// Concurrent calls with one key are serialized by the key's primary key:
+// the later call waits, then returns the user the earlier one created. If
+// that user has since been deleted, ErrDuplicate is returned.
+func (f *Frontend) CreateUserIdempotent(ctx context.Context, key, username, email string) (*User, error) {
+	return instrumentResult(ctx, f, "CreateUserIdempotent", func(ctx context.Context) (*User, error) {
+		if err := f.validateIdempotent(key, username, email); err != nil {
+			return nil, err
+		}
+
+		var user *User
+		run := func(tx *Tx) error {
+			var err error
+			user, err = tx.createUserIdempotent(ctx, key, username, email)
+			return err
+		}
+		err := f.inTransaction(ctx, run)
+		if errors.Is(err, errIdempotencyRace) {
+			// The winner has committed by now, so reading the key again
+			// finds its user
+			err = f.inTransaction(ctx, run)
+		}
+		if errors.Is(err, errIdempotencyRace) {
+			return nil, duplicateError()
+		}
+		return user, err
+	})
+}
+
+// CreateUserIdempotent is CreateUser guarded by key within the transaction.
+// Unlike Frontend.CreateUserIdempotent it cannot wait out a concurrent call
+// with the same key: PostgreSQL aborts the transaction on the key conflict,
+// so ErrDuplicate is returned and the caller should retry the whole
+// transaction.
+func (t *Tx) CreateUserIdempotent(ctx context.Context, key, username, email string) (*User, error) {
+	if err := t.f.validateIdempotent(key, username, email); err != nil {
+		return nil, err
+	}
+	user, err := t.createUserIdempotent(ctx, key, username, email)
+	if errors.Is(err, errIdempotencyRace) {
+		return nil, duplicateError()
+	}
+	return user, err
+}
+
+// createUserIdempotent replays or claims key, creating the user in the
+// latter case. Input must already be validated.
+func (t *Tx) createUserIdempotent(ctx context.Context, key, username, email string) (*User, error) {
+	f := t.f
+	table := f.config.IdempotencyTable
+	now := time.Now()
+
+	// Forget the key once it expires, so it acts as a new request
+	purge := fmt.Sprintf(`DELETE FROM %s WHERE idempotency_key = $1 AND created_at <= $2`, table)
+	if _, err := f.exec(ctx, t.tx, purge, key, now.Add(-f.config.idempotencyKeyTTL())); err != nil {
+		return nil, databaseError(err)
+	}
+
+	var userID sql.NullInt64
+	lookup := fmt.Sprintf(`SELECT user_id FROM %s WHERE idempotency_key = $1`, table)
+	err := f.queryRow(ctx, t.tx, lookup, key).Scan(&userID)
+	if err == nil {
+		if !userID.Valid {
+			return nil, duplicateError()
+		}
+		user, err := f.getUserByID(ctx, t.tx, userID.Int64)
+		if errors.Is(err, ErrNotFound) {
+			return nil, duplicateError()
+		}
+		return user, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, databaseError(err)
+	}
+
+	// Claim the key before inserting the user, so a concurrent request with
+	// the same key waits on the key's index instead of racing on username
+	claim := fmt.Sprintf(`INSERT INTO %s (idempotency_key, created_at) VALUES ($1, $2)`, table)
+	if _, err := f.exec(ctx, t.tx, claim, key, now); err != nil {
+		if isUniqueViolation(err) {
+			return nil, errIdempotencyRace
+		}
+		return nil, databaseError(err)
+	}
+
+	user, err := txAuditWrite(ctx, t, "CreateUserIdempotent", func(q querier) (*User, []int64, error) {
+		return createdUser(f.createUser(ctx, q, username, email))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := fmt.Sprintf(`UPDATE %s SET user_id = $1 WHERE idempotency_key = $2`, table)
+	if _, err := f.exec(ctx, t.tx, record, user.ID, key); err != nil {
+		return nil, databaseError(err)
+	}
+	return user, nil
+}
+
+// validateIdempotent checks that idempotency keys are enabled and applies
+// the CreateUser rules before the key is looked up
+func (f *Frontend) validateIdempotent(key, username, email string) error {
+	if f.config.IdempotencyTable == "" {
+		return fmt.Errorf("%w: idempotency keys are not enabled", ErrInvalidInput)
+	}
+	if key == "" {
+		return invalidField("key", CodeRequired, "idempotency key is required")
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return invalidField("key", CodeTooLong, "idempotency key too long")
+	}
+	if err := f.validateNewUsername(username); err != nil {
+		return err
+	}
+	return f.validateNewEmail(email)
+}
+
+// validateIdempotencyConfig checks the idempotency table name and key TTL
+func validateIdempotencyConfig(config *Config) error {
+	if config.IdempotencyTable != "" && !tableNamePattern.MatchString(config.IdempotencyTable) {
+		return invalidField("IdempotencyTable", CodeInvalidFormat, "invalid idempotency table name")
+	}
+	if config.IdempotencyKeyTTL < 0 {
+		return invalidField("IdempotencyKeyTTL", CodeOutOfRange, "idempotency key TTL must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newIdempotentFrontend returns a Frontend with idempotency keys enabled over
+// a fake store. The store keeps every table in one set of rows, which is
+// enough here because key rows and users never match each other's WHERE
+// clauses.
+func newIdempotentFrontend(t *testing.T) (*Frontend, *fakeStore) {
+	t.Helper()
+	db, store := newFakeDB(t)
+	config := DefaultConfig()
+	config.IdempotencyTable = "idempotency_keys"
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	return f, store
+}
+
+// countUsers returns how many rows in store have a username
+func countUsers(store *fakeStore) int {
+	n := 0
+	for _, row := range store.all() {
+		if row["username"] != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// backdateKey moves the creation time of key back by age
+func backdateKey(t *testing.T, store *fakeStore, key string, age time.Duration) {
+	t.Helper()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, row := range store.rows {
+		if row["idempotency_key"] == key {
+			row["created_at"] = row["created_at"].(time.Time).Add(-age)
+			return
+		}
+	}
+	t.Fatalf("no idempotency key %q", key)
+}
+
+func TestCreateUserIdempotentReplaysKey(t *testing.T) {
+	f, store := newIdempotentFrontend(t)
+	ctx := context.Background()
+
+	first, err := f.CreateUserIdempotent(ctx, "signup-1", "alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("first CreateUserIdempotent = %v, want nil", err)
+	}
+	// A retry returns the original user, whatever it passes
+	again, err := f.CreateUserIdempotent(ctx, "signup-1", "bob", "bob@example.com")
+	if err != nil {
+		t.Fatalf("repeated CreateUserIdempotent = %v, want nil", err)
+	}
+	if again.ID != first.ID || again.Username != "alice" || again.Email != "alice@example.com" {
+		t.Errorf("repeated key returned %+v, want the original user %+v", again, first)
+	}
+	if n := countUsers(store); n != 1 {
+		t.Errorf("store holds %d users, want 1", n)
+	}
+
+	other, err := f.CreateUserIdempotent(ctx, "signup-2", "bob", "bob@example.com")
+	if err != nil {
+		t.Fatalf("CreateUserIdempotent with a new key = %v, want nil", err)
+	}
+	if other.ID == first.ID {
+		t.Errorf("a new key returned the user of another key")
+	}
+}
+
+func TestCreateUserIdempotentExpiredKeyCreatesUser(t *testing.T) {
+	f, store := newIdempotentFrontend(t)
+	ctx := context.Background()
+
+	first, err := f.CreateUserIdempotent(ctx, "signup-1", "alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("first CreateUserIdempotent = %v, want nil", err)
+	}
+	backdateKey(t, store, "signup-1", f.config.idempotencyKeyTTL()+time.Second)
+
+	second, err := f.CreateUserIdempotent(ctx, "signup-1", "bob", "bob@example.com")
+	if err != nil {
+		t.Fatalf("CreateUserIdempotent after expiry = %v, want nil", err)
+	}
+	if second.ID == first.ID || second.Username != "bob" {
+		t.Errorf("expired key returned %+v, want a new user bob", second)
+	}
+	if n := countUsers(store); n != 2 {
+		t.Errorf("store holds %d users, want 2", n)
+	}
+}
+
+func TestCreateUserIdempotentKeyOfDeletedUser(t *testing.T) {
+	f, _ := newIdempotentFrontend(t)
+	ctx := context.Background()
+
+	user, err := f.CreateUserIdempotent(ctx, "signup-1", "alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("CreateUserIdempotent = %v, want nil", err)
+	}
+	if err := f.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser = %v, want nil", err)
+	}
+	if _, err := f.CreateUserIdempotent(ctx, "signup-1", "alice", "alice@example.com"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("CreateUserIdempotent after delete = %v, want ErrDuplicate", err)
+	}
+}
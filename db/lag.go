@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLagCheckInterval is used when ReadConfig.LagCheckInterval is zero
+const defaultLagCheckInterval = time.Second
+
+// ReplicaLag returns how far the read replica's replay is behind the
+// primary, measured on the replica as the time since the last replayed
+// transaction. A replica that has replayed all received WAL reports zero,
+// so an idle primary does not look like lag. Only PostgreSQL is supported.
+func (f *Frontend) ReplicaLag(ctx context.Context) (time.Duration, error) {
+	return instrumentResult(ctx, f, "ReplicaLag", func(ctx context.Context) (time.Duration, error) {
+		_, replica := f.pools()
+		if replica == nil {
+			return 0, fmt.Errorf("%w: no read replica is configured", ErrInvalidInput)
+		}
+		return f.replicaLag(ctx, replica)
+	})
+}
+
+// replicaLag queries the replay delay of a PostgreSQL standby
+func (f *Frontend) replicaLag(ctx context.Context, q querier) (time.Duration, error) {
+	if f.config.driver() != DriverPostgres {
+		return 0, fmt.Errorf("%w: replica lag requires postgres", ErrUnsupported)
+	}
+
+	// NULL when the server is not in recovery, i.e. not a replica
+	query := `SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)`
+
+	var seconds float64
+	if err := f.queryRow(ctx, q, query).Scan(&seconds); err != nil {
+		return 0, databaseError(err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// lagMonitor polls the replica's lag in the background so reader can fall
+// back to the primary without a query of its own
+type lagMonitor struct {
+	lagging  atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// startLagMonitor begins polling when ReadConfig.MaxLag is set
+func (f *Frontend) startLagMonitor() {
+	rc := f.config.ReadReplica
+	if rc == nil || rc.MaxLag <= 0 || f.replica == nil {
+		return
+	}
+	interval := rc.LagCheckInterval
+	if interval == 0 {
+		interval = defaultLagCheckInterval
+	}
+
+	m := &lagMonitor{stop: make(chan struct{}), done: make(chan struct{})}
+	f.lag = m
+	f.checkLag(rc.MaxLag)
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				f.checkLag(rc.MaxLag)
+			}
+		}
+	}()
+}
+
+// checkLag measures the lag once and records whether reads should avoid the
+// replica. A failed measurement counts as lagging, since the replica's state
+// is unknown.
+func (f *Frontend) checkLag(maxLag time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.connectTimeout())
+	defer cancel()
+
+	_, replica := f.pools()
+	lag, err := f.replicaLag(ctx, replica)
+	lagging := err != nil || lag > maxLag
+	if f.lag.lagging.Swap(lagging) != lagging {
+		if lagging {
+			f.logf("read replica lagging (lag %v, error %v); reading from primary", lag, err)
+		} else {
+			f.logf("read replica caught up (lag %v); reading from replica", lag)
+		}
+	}
+}
+
+// replicaLagging reports whether the monitor has diverted reads to the primary
+func (f *Frontend) replicaLagging() bool {
+	return f.lag != nil && f.lag.lagging.Load()
+}
+
+// stopLagMonitor stops polling and waits for the goroutine to exit
+func (f *Frontend) stopLagMonitor() {
+	if f.lag == nil {
+		return
+	}
+	f.lag.stopOnce.Do(func() { close(f.lag.stop) })
+	<-f.lag.done
+}
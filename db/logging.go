@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// Logger receives diagnostic messages, such as rollback failures, that
+// cannot be returned to the caller. *log.Logger satisfies it; adapt other
+// loggers (for example *slog.Logger) with a small wrapper. Messages are
+// already sanitized and never contain credentials.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// logf writes to the configured Logger, defaulting to the standard logger
+func (f *Frontend) logf(format string, args ...any) {
+	f.config.logf(format, args...)
+}
+
+// logfContext is logf for messages about an operation running under ctx. The
+// operation name recorded by instrument and the request ID, when present,
+// are prefixed as fields, for example
+//
+//	[op=UpdateUser request_id="req-42"] rollback error: ...
+//
+// The request ID is quoted so that a client-supplied value cannot forge log
+// lines; operation names are this package's method names.
+func (f *Frontend) logfContext(ctx context.Context, format string, args ...any) {
+	var fields []string
+	var values []any
+	if op := opFromContext(ctx); op != "" {
+		fields = append(fields, "op=%s")
+		values = append(values, op)
+	}
+	if id := f.requestID(ctx); id != "" {
+		fields = append(fields, "request_id=%q")
+		values = append(values, id)
+	}
+	if len(fields) > 0 {
+		format = "[" + strings.Join(fields, " ") + "] " + format
+		args = append(values, args...)
+	}
+	f.logf(format, args...)
+}
+
+// logf is used where no Frontend exists yet, such as while connecting
+func (c *Config) logf(format string, args ...any) {
+	logger := c.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf(format, args...)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// defaultMaxMetadataBytes is used when Config.MaxMetadataBytes is zero
+const defaultMaxMetadataBytes = 64 << 10
+
+// maxMetadataBytes returns the configured metadata size cap or the default
+func (c *Config) maxMetadataBytes() int {
+	if c.MaxMetadataBytes == 0 {
+		return defaultMaxMetadataBytes
+	}
+	return c.MaxMetadataBytes
+}
+
+// UpdateUserMetadata replaces the JSON metadata of a user, stored in
+// Schema.MetadataColumn. metadata must be a JSON object no larger than
+// Config.MaxMetadataBytes; nil or JSON null clears it.
+func (f *Frontend) UpdateUserMetadata(ctx context.Context, userID int64, metadata json.RawMessage) error {
+	return f.instrument(ctx, "UpdateUserMetadata", func(ctx context.Context) error {
+		return f.auditExec(ctx, "UpdateUserMetadata", userID, func(q querier) error {
+			return f.updateUserMetadata(ctx, q, userID, metadata)
+		})
+	})
+}
+
+// UpdateUserMetadata replaces the JSON metadata of a user within the
+// transaction
+func (t *Tx) UpdateUserMetadata(ctx context.Context, userID int64, metadata json.RawMessage) error {
+	return t.auditExec(ctx, "UpdateUserMetadata", userID, func(q querier) error {
+		return t.f.updateUserMetadata(ctx, q, userID, metadata)
+	})
+}
+
+// updateUserMetadata validates metadata and writes it to the metadata column
+func (f *Frontend) updateUserMetadata(ctx context.Context, q querier, userID int64, metadata json.RawMessage) error {
+	if f.schema.MetadataColumn == "" {
+		return fmt.Errorf("%w: Schema.MetadataColumn is not set", ErrInvalidInput)
+	}
+	if err := validateMetadata(metadata, f.config.maxMetadataBytes()); err != nil {
+		return err
+	}
+
+	// Bound as text, which every driver converts to its JSON column type
+	var value any
+	if len(metadata) > 0 && !bytes.Equal(bytes.TrimSpace(metadata), []byte("null")) {
+		value = string(metadata)
+	}
+	return f.updateUserColumn(ctx, q, userID, f.schema.MetadataColumn, value)
+}
+
+// validateMetadata checks that metadata is a JSON object, or empty or null,
+// of at most maxBytes bytes
+func validateMetadata(metadata json.RawMessage, maxBytes int) error {
+	if len(metadata) > maxBytes {
+		return invalidField("metadata", CodeTooLong, fmt.Sprintf("metadata exceeds %d bytes", maxBytes))
+	}
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	if trimmed[0] != '{' || !json.Valid(trimmed) {
+		return invalidField("metadata", CodeInvalidFormat, "metadata must be a JSON object")
+	}
+	return nil
+}
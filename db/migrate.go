@@ -0,0 +1,338 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migrationsTable records which migrations have been applied
+const migrationsTable = "schema_migrations"
+
+// migration is one schema change. Versions are never reused or reordered;
+// new changes are appended with the next version number.
+type migration struct {
+	version int64
+	name    string
+	// enabled reports whether the configuration needs this migration. A
+	// disabled migration is skipped without being recorded, so it runs
+	// later if the feature is switched on.
+	enabled func(c *Config) bool
+	// statements returns the DDL to run, built from validated identifiers
+	statements func(f *Frontend) []string
+	// check, when set, rejects a driver the migration cannot run on before
+	// any pending migration is applied
+	check func(c *Config) error
+}
+
+// migrations lists every schema change in the order it is applied
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_users",
+		statements: func(f *Frontend) []string {
+			s, d := f.schema, f.ddlTypes()
+			return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s %s,
+	%s VARCHAR(50) NOT NULL UNIQUE,
+	%s VARCHAR(254) NOT NULL UNIQUE,
+	%s %s NOT NULL,
+	%s VARCHAR(255)
+)`, s.Table, s.IDColumn, d.id, s.UsernameColumn, s.EmailColumn,
+				s.CreatedAtColumn, d.timestamp, s.PasswordHashColumn)}
+		},
+	},
+	{
+		version: 2,
+		name:    "add_users_deleted_at",
+		enabled: func(c *Config) bool { return c.SoftDelete },
+		statements: func(f *Frontend) []string {
+			return []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`,
+				f.schema.Table, f.schema.DeletedAtColumn, f.ddlTypes().timestamp)}
+		},
+	},
+	{
+		version: 3,
+		name:    "add_users_version",
+		enabled: func(c *Config) bool { return c.OptimisticLocking },
+		statements: func(f *Frontend) []string {
+			return []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s BIGINT NOT NULL DEFAULT 1`,
+				f.schema.Table, f.schema.VersionColumn)}
+		},
+	},
+	{
+		version: 4,
+		name:    "create_audit_log",
+		enabled: func(c *Config) bool { return c.AuditTable != "" },
+		statements: func(f *Frontend) []string {
+			d := f.ddlTypes()
+			return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	operation VARCHAR(64) NOT NULL,
+	user_id BIGINT NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	occurred_at %s NOT NULL
+)`, f.config.AuditTable, d.id, d.timestamp)}
+		},
+	},
+	{
+		version: 5,
+		name:    "add_users_username_lower_index",
+		enabled: func(c *Config) bool { return c.CaseInsensitiveUsernames },
+		statements: func(f *Frontend) []string {
+			s := f.schema
+			return []string{fmt.Sprintf(`CREATE UNIQUE INDEX %s ON %s ((LOWER(%s)))`,
+				f.usernameLowerIndexName(), s.Table, s.UsernameColumn)}
+		},
+	},
+	{
+		version: 6,
+		name:    "add_users_uuid",
+		enabled: func(c *Config) bool { return c.Schema.UUIDColumn != "" },
+		statements: func(f *Frontend) []string {
+			s := f.schema
+			name := strings.ReplaceAll(s.Table, ".", "_") + "_" + s.UUIDColumn + "_key"
+			return []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, s.Table, s.UUIDColumn, f.ddlTypes().uuid),
+				fmt.Sprintf(`CREATE UNIQUE INDEX %s ON %s (%s)`, name, s.Table, s.UUIDColumn),
+			}
+		},
+	},
+	{
+		version: 7,
+		name:    "add_users_updated_at",
+		enabled: func(c *Config) bool { return c.TrackUpdatedAt },
+		statements: func(f *Frontend) []string {
+			return []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`,
+				f.schema.Table, f.schema.UpdatedAtColumn, f.ddlTypes().timestamp)}
+		},
+	},
+	{
+		version: 8,
+		name:    "add_users_tenant",
+		enabled: func(c *Config) bool { return c.Schema.TenantColumn != "" },
+		check: func(c *Config) error {
+			if c.driver() == DriverSQLite {
+				return fmt.Errorf("%w: sqlite cannot drop the unique constraints of an existing table; "+
+					"add Schema.TenantColumn and per-tenant unique indexes by hand", ErrUnsupported)
+			}
+			return nil
+		},
+		statements: func(f *Frontend) []string {
+			s := f.schema
+			// Existing rows belong to no tenant until they are assigned one
+			stmts := []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s VARCHAR(255) NOT NULL DEFAULT ''`, s.Table, s.TenantColumn)}
+			for _, column := range []string{s.UsernameColumn, s.EmailColumn} {
+				name := strings.ReplaceAll(s.Table, ".", "_") + "_" + s.TenantColumn + "_" + column + "_key"
+				stmts = append(stmts,
+					f.dropInlineUnique(column),
+					fmt.Sprintf(`CREATE UNIQUE INDEX %s ON %s (%s, %s)`, name, s.Table, s.TenantColumn, column))
+			}
+			return stmts
+		},
+	},
+	{
+		// add_users_username_lower_index creates a global index and always
+		// runs first, so this replaces it whichever option was enabled first
+		version: 9,
+		name:    "add_users_tenant_username_lower_key",
+		enabled: func(c *Config) bool { return c.CaseInsensitiveUsernames && c.Schema.TenantColumn != "" },
+		statements: func(f *Frontend) []string {
+			s := f.schema
+			return []string{
+				f.dropIndex(f.usernameLowerIndexName()),
+				fmt.Sprintf(`CREATE UNIQUE INDEX %s ON %s (%s, (LOWER(%s)))`,
+					f.usernameLowerIndexName(), s.Table, s.TenantColumn, s.UsernameColumn),
+			}
+		},
+	},
+	{
+		version: 10,
+		name:    "add_users_metadata",
+		enabled: func(c *Config) bool { return c.Schema.MetadataColumn != "" },
+		statements: func(f *Frontend) []string {
+			return []string{fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`,
+				f.schema.Table, f.schema.MetadataColumn, f.ddlTypes().json)}
+		},
+	},
+	{
+		version: 11,
+		name:    "create_idempotency_keys",
+		enabled: func(c *Config) bool { return c.IdempotencyTable != "" },
+		statements: func(f *Frontend) []string {
+			return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	idempotency_key VARCHAR(255) PRIMARY KEY,
+	user_id BIGINT,
+	created_at %s NOT NULL
+)`, f.config.IdempotencyTable, f.ddlTypes().timestamp)}
+		},
+	},
+	{
+		version: 12,
+		name:    "add_users_email_verification",
+		enabled: func(c *Config) bool { return c.EmailVerification },
+		statements: func(f *Frontend) []string {
+			s := f.schema
+			// SQLite adds one column per ALTER TABLE
+			name := strings.ReplaceAll(s.Table, ".", "_") + "_" + s.VerificationTokenColumn + "_idx"
+			return []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s BOOLEAN NOT NULL DEFAULT FALSE`, s.Table, s.EmailVerifiedColumn),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s VARCHAR(64)`, s.Table, s.VerificationTokenColumn),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, s.Table, s.VerificationSentAtColumn, f.ddlTypes().timestamp),
+				fmt.Sprintf(`CREATE INDEX %s ON %s (%s)`, name, s.Table, s.VerificationTokenColumn),
+			}
+		},
+	},
+}
+
+// Migrate creates or upgrades the tables this package uses, following the
+// configured Schema: the users table, plus the soft-delete, version, UUID,
+// updated-at, tenant, metadata and email verification columns, the audit and
+// idempotency tables and the case-insensitive username index when those
+// features are enabled. With a tenant column, usernames and emails are unique
+// per tenant rather than globally; SQLite cannot drop the original
+// constraints, so Migrate returns ErrUnsupported there. Applied migrations
+// are recorded in schema_migrations and never re-run, so Migrate is safe to
+// call on every deploy. It never runs implicitly.
+//
+// Each migration runs in its own transaction. PostgreSQL and SQLite roll back
+// a failed migration completely; MySQL commits DDL implicitly, so a failure
+// there may need manual cleanup. Run Migrate from a single process at a time.
+func (f *Frontend) Migrate(ctx context.Context) error {
+	return f.instrument(ctx, "Migrate", func(ctx context.Context) error {
+		return f.migrate(ctx)
+	})
+}
+
+// migrate applies every enabled migration that has not been recorded yet
+func (f *Frontend) migrate(ctx context.Context) error {
+	d := f.ddlTypes()
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at %s NOT NULL
+)`, migrationsTable, d.timestamp)
+	if _, err := f.exec(ctx, f.primary(), create); err != nil {
+		return databaseError(err)
+	}
+
+	applied, err := f.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if applied[m.version] || (m.enabled != nil && !m.enabled(f.config)) {
+			continue
+		}
+		if m.check != nil {
+			if err := m.check(f.config); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+		pending = append(pending, m)
+	}
+
+	for _, m := range pending {
+		err := f.inTransaction(ctx, func(tx *Tx) error {
+			for _, stmt := range m.statements(f) {
+				if _, err := f.exec(ctx, tx.tx, stmt); err != nil {
+					return databaseError(err)
+				}
+			}
+			record := fmt.Sprintf(`INSERT INTO %s (version, name, applied_at) VALUES ($1, $2, $3)`, migrationsTable)
+			if _, err := f.exec(ctx, tx.tx, record, m.version, m.name, time.Now()); err != nil {
+				return databaseError(err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		f.logfContext(ctx, "applied migration %d (%s)", m.version, m.name)
+	}
+	return nil
+}
+
+// appliedMigrations returns the recorded migration versions
+func (f *Frontend) appliedMigrations(ctx context.Context) (map[int64]bool, error) {
+	rows, err := f.query(ctx, f.primary(), fmt.Sprintf(`SELECT version FROM %s`, migrationsTable))
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, databaseError(err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return applied, nil
+}
+
+// usernameLowerIndexName names the case-insensitive username index. Indexes
+// live in the table's schema, so the name is unqualified.
+func (f *Frontend) usernameLowerIndexName() string {
+	return strings.ReplaceAll(f.schema.Table, ".", "_") + "_" + f.schema.UsernameColumn + "_lower_key"
+}
+
+// dropIndex drops an index of the users table by name
+func (f *Frontend) dropIndex(name string) string {
+	s := f.schema
+	switch f.config.driver() {
+	case DriverMySQL:
+		return fmt.Sprintf(`DROP INDEX %s ON %s`, name, s.Table)
+	default:
+		// PostgreSQL looks the name up in the table's schema
+		if schema, _, ok := strings.Cut(s.Table, "."); ok {
+			name = schema + "." + name
+		}
+		return `DROP INDEX ` + name
+	}
+}
+
+// dropInlineUnique drops the UNIQUE constraint create_users declares on
+// column, under the name each driver gives it: <table>_<column>_key on
+// PostgreSQL and the column name on MySQL
+func (f *Frontend) dropInlineUnique(column string) string {
+	s := f.schema
+	if f.config.driver() == DriverMySQL {
+		return fmt.Sprintf(`ALTER TABLE %s DROP INDEX %s`, s.Table, column)
+	}
+	table := s.Table[strings.LastIndex(s.Table, ".")+1:]
+	return fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s_%s_key`, s.Table, table, column)
+}
+
+// ddlColumnTypes holds the driver-specific column types used in migrations
+type ddlColumnTypes struct {
+	id        string // auto-incrementing primary key
+	timestamp string
+	// uuid generates a value for existing and new rows where the driver can;
+	// SQLite cannot add a column with a non-constant default, so its rows
+	// get a UUID only from CreateUserWithUUID
+	uuid string
+	json string
+}
+
+// ddlTypes returns the column types for the configured driver
+func (f *Frontend) ddlTypes() ddlColumnTypes {
+	switch f.config.driver() {
+	case DriverMySQL:
+		return ddlColumnTypes{id: "BIGINT AUTO_INCREMENT PRIMARY KEY", timestamp: "DATETIME(6)",
+			uuid: "CHAR(36) NOT NULL DEFAULT (UUID())", json: "JSON"}
+	case DriverSQLite:
+		return ddlColumnTypes{id: "INTEGER PRIMARY KEY AUTOINCREMENT", timestamp: "TIMESTAMP",
+			uuid: "CHAR(36)", json: "TEXT"}
+	default:
+		return ddlColumnTypes{id: "BIGSERIAL PRIMARY KEY", timestamp: "TIMESTAMPTZ",
+			uuid: "UUID NOT NULL DEFAULT gen_random_uuid()", json: "JSONB"}
+	}
+}
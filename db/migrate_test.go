@@ -0,0 +1,152 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// migrationStatements returns the statements of the named migration for a
+// Frontend built from config
+func migrationStatements(t *testing.T, config *Config, name string) []string {
+	t.Helper()
+	db, _ := newFakeDB(t)
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	for _, m := range migrations {
+		if m.name == name {
+			if m.enabled != nil && !m.enabled(config) {
+				t.Fatalf("migration %s is not enabled", name)
+			}
+			return m.statements(f)
+		}
+	}
+	t.Fatalf("no migration %s", name)
+	return nil
+}
+
+func TestMigrationVersionsIncrease(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d", m.name, m.version, i+1)
+		}
+	}
+}
+
+func TestUUIDMigration(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		column string
+	}{
+		{DriverPostgres, `ALTER TABLE users ADD COLUMN uuid UUID NOT NULL DEFAULT gen_random_uuid()`},
+		{DriverMySQL, `ALTER TABLE users ADD COLUMN uuid CHAR(36) NOT NULL DEFAULT (UUID())`},
+		{DriverSQLite, `ALTER TABLE users ADD COLUMN uuid CHAR(36)`},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		config.Schema.UUIDColumn = "uuid"
+
+		got := migrationStatements(t, config, "add_users_uuid")
+		want := []string{tt.column, `CREATE UNIQUE INDEX users_uuid_key ON users (uuid)`}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: statements = %q, want %q", tt.driver, got, want)
+		}
+	}
+}
+
+func TestTenantMigration(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		table  string
+		want   []string
+	}{
+		{DriverPostgres, "app.users", []string{
+			`ALTER TABLE app.users ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE app.users DROP CONSTRAINT users_username_key`,
+			`CREATE UNIQUE INDEX app_users_tenant_id_username_key ON app.users (tenant_id, username)`,
+			`ALTER TABLE app.users DROP CONSTRAINT users_email_key`,
+			`CREATE UNIQUE INDEX app_users_tenant_id_email_key ON app.users (tenant_id, email)`,
+		}},
+		{DriverMySQL, "users", []string{
+			`ALTER TABLE users ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE users DROP INDEX username`,
+			`CREATE UNIQUE INDEX users_tenant_id_username_key ON users (tenant_id, username)`,
+			`ALTER TABLE users DROP INDEX email`,
+			`CREATE UNIQUE INDEX users_tenant_id_email_key ON users (tenant_id, email)`,
+		}},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		config.Schema.Table = tt.table
+		config.Schema.TenantColumn = "tenant_id"
+
+		got := migrationStatements(t, config, "add_users_tenant")
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: statements = %q, want %q", tt.driver, got, tt.want)
+		}
+	}
+}
+
+func TestTenantUsernameLowerIndexMigration(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		drop   string
+	}{
+		{DriverPostgres, `DROP INDEX app.app_users_username_lower_key`},
+		{DriverMySQL, `DROP INDEX app_users_username_lower_key ON app.users`},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		config.Schema.Table = "app.users"
+		config.Schema.TenantColumn = "tenant_id"
+		config.CaseInsensitiveUsernames = true
+
+		got := migrationStatements(t, config, "add_users_tenant_username_lower_key")
+		want := []string{tt.drop,
+			`CREATE UNIQUE INDEX app_users_username_lower_key ON app.users (tenant_id, (LOWER(username)))`}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: statements = %q, want %q", tt.driver, got, want)
+		}
+	}
+}
+
+func TestTenantMigrationUnsupportedOnSQLite(t *testing.T) {
+	config := DefaultConfig()
+	config.Driver = DriverSQLite
+	config.Schema.TenantColumn = "tenant_id"
+	for _, m := range migrations {
+		if m.name == "add_users_tenant" {
+			if err := m.check(config); !errors.Is(err, ErrUnsupported) {
+				t.Fatalf("check = %v, want ErrUnsupported", err)
+			}
+			return
+		}
+	}
+	t.Fatal("no migration add_users_tenant")
+}
+
+func TestMetadataMigration(t *testing.T) {
+	tests := []struct {
+		driver Driver
+		want   string
+	}{
+		{DriverPostgres, `ALTER TABLE users ADD COLUMN metadata JSONB`},
+		{DriverMySQL, `ALTER TABLE users ADD COLUMN metadata JSON`},
+		{DriverSQLite, `ALTER TABLE users ADD COLUMN metadata TEXT`},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Driver = tt.driver
+		config.Schema.MetadataColumn = "metadata"
+
+		got := migrationStatements(t, config, "add_users_metadata")
+		if want := []string{tt.want}; !slices.Equal(got, want) {
+			t.Errorf("%s: statements = %q, want %q", tt.driver, got, want)
+		}
+	}
+}
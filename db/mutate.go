@@ -0,0 +1,74 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// MutateUser is a read-modify-write of one user in a single transaction. It
+// selects the user FOR UPDATE, so concurrent writers wait, passes a copy to
+// fn, and writes back the username, email and metadata if fn changed them,
+// with the same validation as UpdateUser and UpdateUserMetadata. Changing
+// any other field returns ErrInvalidInput. An error from fn rolls the
+// transaction back and is returned as is.
+//
+// The returned user is the row as stored afterwards. fn should be quick and
+// must not call other Frontend methods, since the row stays locked until it
+// returns.
+func (f *Frontend) MutateUser(ctx context.Context, userID int64, fn func(*User) error) (*User, error) {
+	return instrumentResult(ctx, f, "MutateUser", func(ctx context.Context) (*User, error) {
+		var user *User
+		err := f.inTransaction(ctx, func(tx *Tx) error {
+			var err error
+			user, err = tx.MutateUser(ctx, userID, fn)
+			return err
+		})
+		return user, err
+	})
+}
+
+// MutateUser is a read-modify-write of one user within the transaction; the
+// row stays locked until the transaction ends
+func (t *Tx) MutateUser(ctx context.Context, userID int64, fn func(*User) error) (*User, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("%w: mutate function is required", ErrInvalidInput)
+	}
+	current, err := t.f.getUserByIDForUpdate(ctx, t.tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *current
+	next.Metadata = bytes.Clone(current.Metadata)
+	if err := fn(&next); err != nil {
+		return nil, err
+	}
+	if next.ID != current.ID || next.UUID != current.UUID || next.Version != current.Version ||
+		next.EmailVerified != current.EmailVerified || !next.CreatedAt.Equal(current.CreatedAt) || !next.UpdatedAt.Equal(current.UpdatedAt) {
+		return nil, fmt.Errorf("%w: only username, email and metadata can be changed", ErrInvalidInput)
+	}
+
+	fieldsChanged := next.Username != current.Username || next.Email != current.Email
+	metadataChanged := !bytes.Equal(next.Metadata, current.Metadata)
+	if !fieldsChanged && !metadataChanged {
+		return current, nil
+	}
+
+	err = t.auditExec(ctx, "MutateUser", userID, func(q querier) error {
+		if fieldsChanged {
+			if err := t.f.updateUser(ctx, q, userID, next.Username, next.Email); err != nil {
+				return err
+			}
+		}
+		if metadataChanged {
+			return t.f.updateUserMetadata(ctx, q, userID, next.Metadata)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Read back the version and timestamps the updates set
+	return t.f.getUserByID(ctx, t.tx, userID)
+}
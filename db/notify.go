@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// UserChange is the payload of a change notification. Like AuditEvent it
+// never contains usernames, emails or other field values.
+type UserChange struct {
+	Operation string `json:"op"` // public method name, e.g. "UpdateUser"
+	UserID    int64  `json:"user_id"`
+}
+
+// Listener is a dedicated PostgreSQL connection that can LISTEN, which
+// database/sql cannot express. Adapt the driver's own type, for example a
+// *pgx.Conn (Exec "LISTEN" and WaitForNotification) or a *pq.Listener. It
+// must not be a connection from the Frontend's pool.
+type Listener interface {
+	// Listen subscribes the connection to channel
+	Listen(ctx context.Context, channel string) error
+	// WaitForNotification blocks until a notification arrives on a
+	// subscribed channel or ctx ends, and returns its payload
+	WaitForNotification(ctx context.Context) (payload string, err error)
+	// Close releases the connection
+	Close() error
+}
+
+// SubscribeUserChanges listens on Config.NotifyChannel through l and
+// delivers a UserChange for every committed write, for example to
+// invalidate caches. It takes ownership of l: when ctx is cancelled or the
+// listener fails, l is closed and so is the returned channel, and callers
+// that need to keep listening subscribe again with a fresh Listener.
+// Notifications that are not valid UserChange payloads are skipped.
+func (f *Frontend) SubscribeUserChanges(ctx context.Context, l Listener) (<-chan UserChange, error) {
+	if f.config.NotifyChannel == "" {
+		return nil, fmt.Errorf("%w: Config.NotifyChannel is not set", ErrInvalidInput)
+	}
+	if l == nil {
+		return nil, fmt.Errorf("%w: listener is required", ErrInvalidInput)
+	}
+	if err := l.Listen(ctx, f.config.NotifyChannel); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("%w: listen failed: %v", ErrConnectionFailed, sanitizeError(err))
+	}
+
+	changes := make(chan UserChange)
+	go func() {
+		defer close(changes)
+		defer l.Close()
+
+		for {
+			payload, err := l.WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					f.logf("change listener stopped: %v", sanitizeError(err))
+				}
+				return
+			}
+
+			var change UserChange
+			if err := json.Unmarshal([]byte(payload), &change); err != nil || change.Operation == "" {
+				f.logf("ignoring malformed change notification")
+				continue
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// notifyChanges sends one pg_notify per event on Config.NotifyChannel. Within
+// a transaction PostgreSQL holds the notifications until commit and drops
+// them on rollback.
+func (f *Frontend) notifyChanges(ctx context.Context, q querier, events []AuditEvent) error {
+	if f.config.NotifyChannel == "" {
+		return nil
+	}
+	for _, e := range events {
+		payload, err := json.Marshal(UserChange{Operation: e.Operation, UserID: e.UserID})
+		if err != nil {
+			return fmt.Errorf("%w: encode change notification", ErrDatabaseError)
+		}
+		if _, err := f.exec(ctx, q, `SELECT pg_notify($1, $2)`, f.config.NotifyChannel, string(payload)); err != nil {
+			return fmt.Errorf("change notification: %w", databaseError(err))
+		}
+	}
+	return nil
+}
+
+// validateNotifyChannel checks the configured notification channel
+func validateNotifyChannel(config *Config) error {
+	if config.NotifyChannel == "" {
+		return nil
+	}
+	if !identifierPattern.MatchString(config.NotifyChannel) {
+		return fmt.Errorf("%w: invalid notify channel name", ErrInvalidInput)
+	}
+	if config.driver() != DriverPostgres {
+		return fmt.Errorf("%w: change notifications require postgres", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives timing for every database operation, letting callers
+// export metrics (for example Prometheus histograms) without this package
+// depending on a metrics library. op is the public method name, such as
+// "GetUserByID". Implementations must be safe for concurrent use.
+type Observer interface {
+	ObserveQuery(op string, duration time.Duration, err error)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(op string, duration time.Duration, err error)
+
+// ObserveQuery calls fn
+func (fn ObserverFunc) ObserveQuery(op string, duration time.Duration, err error) {
+	fn(op, duration, err)
+}
+
+// ActorObserver is an Observer that also wants the actor performing each
+// operation (see WithActor and Config.ActorKey), for example to attribute
+// load or keep a per-operation audit trail. When the configured Observer
+// implements it, ObserveQueryActor is called instead of ObserveQuery; actor
+// is "" when the context carries none.
+type ActorObserver interface {
+	Observer
+	ObserveQueryActor(op, actor string, duration time.Duration, err error)
+}
+
+// QueryEvent describes one finished operation for an EventObserver
+type QueryEvent struct {
+	Op        string
+	Actor     string // "" when the context carries none
+	RequestID string // "" when the context carries none
+	Duration  time.Duration
+	Err       error
+}
+
+// EventObserver is an Observer that receives each operation as a QueryEvent,
+// including the request ID (see WithRequestID and Config.RequestIDKey) for
+// correlating metrics with a request. When the configured Observer
+// implements it, ObserveEvent is called instead of ObserveQuery or
+// ObserveQueryActor.
+type EventObserver interface {
+	Observer
+	ObserveEvent(event QueryEvent)
+}
+
+// RowsObserver is an Observer that also wants result sizes, to catch queries
+// that unexpectedly touch thousands of rows. When the configured Observer
+// implements it, ObserveRows is called after every operation with the rows
+// scanned from multi-row results, such as SearchUsers and ListUsers, plus the
+// rows affected by statements that do not return rows. Single-row lookups and
+// RETURNING inserts are not counted.
+type RowsObserver interface {
+	Observer
+	ObserveRows(op string, rows int64)
+}
+
+// rowCounterKey is the context key for the rows counted during an operation
+type rowCounterKey struct{}
+
+// countRows adds n to the row count of the operation running under ctx. It
+// does nothing unless a RowsObserver is configured.
+func countRows(ctx context.Context, n int64) {
+	if counter, ok := ctx.Value(rowCounterKey{}).(*int64); ok {
+		*counter += n
+	}
+}
+
+// instrument runs fn as the operation op: it applies the query timeout, rate
+// limits and connection acquire timeout, counts the call as in flight for
+// Shutdown, wraps it in a trace span, records op in the context for query
+// tagging, and reports the duration and outcome to the configured Observer
+func (f *Frontend) instrument(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	// Create context with timeout
+	ctx, cancel := f.withQueryTimeout(ctx)
+	defer cancel()
+	ctx = withOp(ctx, op)
+	rowsObserver, _ := f.config.Observer.(RowsObserver)
+	var rows int64
+	if rowsObserver != nil {
+		ctx = context.WithValue(ctx, rowCounterKey{}, &rows)
+	}
+
+	ctx, span := f.startSpan(ctx, op)
+	defer span.End()
+
+	start := time.Now()
+	err := f.enter(ctx)
+	if err == nil {
+		defer f.leave()
+		err = f.limiter.admit(ctx, op)
+	}
+	if err == nil {
+		err = f.awaitConn(ctx)
+	}
+	if err == nil {
+		err = fn(ctx)
+	}
+	if err != nil {
+		// Errors returned by this package are already sanitized
+		span.RecordError(err)
+	}
+	duration := time.Since(start)
+	f.observe(ctx, op, duration, err)
+	f.logOperation(ctx, op, duration, err)
+	if rowsObserver != nil {
+		rowsObserver.ObserveRows(op, rows)
+	}
+	return err
+}
+
+// observe reports one operation to the configured Observer
+func (f *Frontend) observe(ctx context.Context, op string, duration time.Duration, err error) {
+	switch o := f.config.Observer.(type) {
+	case nil:
+		// Observation disabled
+	case EventObserver:
+		o.ObserveEvent(QueryEvent{
+			Op:        op,
+			Actor:     f.actor(ctx),
+			RequestID: f.requestID(ctx),
+			Duration:  duration,
+			Err:       err,
+		})
+	case ActorObserver:
+		o.ObserveQueryActor(op, f.actor(ctx), duration, err)
+	default:
+		o.ObserveQuery(op, duration, err)
+	}
+}
+
+// instrumentResult is instrument for operations that return a value
+func instrumentResult[T any](ctx context.Context, f *Frontend, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := f.instrument(ctx, op, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
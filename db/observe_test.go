@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// rowsRecorder is a RowsObserver that keeps the last row count per operation
+type rowsRecorder map[string]int64
+
+func (r rowsRecorder) ObserveQuery(string, time.Duration, error) {}
+
+func (r rowsRecorder) ObserveRows(op string, rows int64) { r[op] = rows }
+
+func TestObserveRowsCountsOnlyTheOperationsStatement(t *testing.T) {
+	db, store := newFakeDB(t)
+	rows := rowsRecorder{}
+	config := DefaultConfig()
+	config.AuditTable = "user_audit"
+	config.Observer = rows
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	id := store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com"})
+
+	if err := f.UpdateUser(context.Background(), id, "alice2", "alice2@example.com"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	// The audit insert runs in the same operation but is not its statement
+	if got := rows["UpdateUser"]; got != 1 {
+		t.Errorf("UpdateUser observed %d rows, want 1", got)
+	}
+}
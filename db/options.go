@@ -0,0 +1,49 @@
+package db
+
+// Option adjusts the configuration a constructor uses, for settings that are
+// easier to pass at the call site than to set on Config. Options are applied
+// in order to a copy of the config, after nil is replaced by DefaultConfig,
+// so the caller's Config is never modified.
+type Option func(*Config)
+
+// WithLogger sets Config.Logger
+func WithLogger(logger Logger) Option {
+	return func(c *Config) { c.Logger = logger }
+}
+
+// WithObserver sets Config.Observer
+func WithObserver(observer Observer) Option {
+	return func(c *Config) { c.Observer = observer }
+}
+
+// WithDriver sets Config.Driver
+func WithDriver(driver Driver) Option {
+	return func(c *Config) { c.Driver = driver }
+}
+
+// WithReadReplica sets Config.ReadReplica. Only NewFrontend supports read
+// replicas.
+func WithReadReplica(replica *ReadConfig) Option {
+	return func(c *Config) { c.ReadReplica = replica }
+}
+
+// WithCredentials sets Config.Credentials
+func WithCredentials(provider CredentialProvider) Option {
+	return func(c *Config) { c.Credentials = provider }
+}
+
+// applyOptions returns config, or DefaultConfig when it is nil, with opts
+// applied to a copy when there are any
+func applyOptions(config *Config, opts []Option) *Config {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if len(opts) == 0 {
+		return config
+	}
+	c := *config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
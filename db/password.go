@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password length limits. bcrypt ignores input beyond 72 bytes, so longer
+// passwords are rejected rather than silently truncated.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// errInvalidCredentials is returned for every VerifyPassword failure so
+// callers cannot tell whether the username or the password was wrong
+var errInvalidCredentials = fmt.Errorf("%w: invalid username or password", ErrInvalidInput)
+
+// CreateUserWithPassword creates a user and stores a bcrypt hash of password
+// in Schema.PasswordHashColumn. The plaintext is never stored or logged.
+func (f *Frontend) CreateUserWithPassword(ctx context.Context, username, email, password string) (*User, error) {
+	return instrumentResult(ctx, f, "CreateUserWithPassword", func(ctx context.Context) (*User, error) {
+		return auditWrite(ctx, f, "CreateUserWithPassword", func(q querier) (*User, []int64, error) {
+			return createdUser(f.createUserWithPassword(ctx, q, username, email, password))
+		})
+	})
+}
+
+// CreateUserWithPassword creates a user with a hashed password within the transaction
+func (t *Tx) CreateUserWithPassword(ctx context.Context, username, email, password string) (*User, error) {
+	return txAuditWrite(ctx, t, "CreateUserWithPassword", func(q querier) (*User, []int64, error) {
+		return createdUser(t.f.createUserWithPassword(ctx, q, username, email, password))
+	})
+}
+
+// VerifyPassword checks password against the stored hash for username and
+// returns the user on success. Any failure, including an unknown username or
+// a user without a password, returns the same ErrInvalidInput error.
+func (f *Frontend) VerifyPassword(ctx context.Context, username, password string) (*User, error) {
+	if validateUsername(username) != nil || validatePassword(password) != nil {
+		return nil, errInvalidCredentials
+	}
+
+	return instrumentResult(ctx, f, "VerifyPassword", func(ctx context.Context) (*User, error) {
+		return f.verifyPassword(ctx, f.primary(), username, password)
+	})
+}
+
+// VerifyPassword checks a password within the transaction
+func (t *Tx) VerifyPassword(ctx context.Context, username, password string) (*User, error) {
+	if validateUsername(username) != nil || validatePassword(password) != nil {
+		return nil, errInvalidCredentials
+	}
+	return t.f.verifyPassword(ctx, t.tx, username, password)
+}
+
+// verifyPassword loads the stored hash for username and compares it
+func (f *Frontend) verifyPassword(ctx context.Context, q querier, username, password string) (*User, error) {
+	s := f.schema
+	match := s.UsernameColumn + " = $1"
+	if f.config.CaseInsensitiveUsernames {
+		match = "LOWER(" + s.UsernameColumn + ") = LOWER($1)"
+	}
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s%s`,
+		f.userColumns(), s.PasswordHashColumn, s.Table, f.where(match))
+
+	var hash sql.NullString
+	user, err := f.scanUser(f.queryRow(ctx, q, query, f.normalizeUsername(username)), &hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, databaseError(err)
+	}
+
+	if err != nil || !hash.Valid {
+		// Spend the same time as a real comparison before failing
+		bcrypt.CompareHashAndPassword(f.dummyPasswordHash(), []byte(password))
+		return nil, errInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(password)) != nil {
+		return nil, errInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// createUserWithPassword validates, hashes and inserts a user with a password
+func (f *Frontend) createUserWithPassword(ctx context.Context, q querier, username, email, password string) (*User, error) {
+	// Validate inputs
+	if err := f.validateNewUsername(username); err != nil {
+		return nil, err
+	}
+	if err := f.validateNewEmail(email); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), f.passwordHashCost())
+	if err != nil {
+		// bcrypt errors never contain the password, but keep the message generic
+		return nil, fmt.Errorf("%w: failed to hash password", ErrInvalidInput)
+	}
+
+	extra := []columnValue{{column: f.schema.PasswordHashColumn, value: string(hash)}}
+	return f.insertUser(ctx, q, username, email, extra)
+}
+
+// passwordHashCost returns the configured bcrypt cost or bcrypt.DefaultCost
+func (f *Frontend) passwordHashCost() int {
+	if f.config.PasswordHashCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return f.config.PasswordHashCost
+}
+
+// dummyPasswordHash lazily builds the hash compared against when a username
+// does not exist, so that the response time does not reveal which usernames
+// are registered. It uses this Frontend's cost, so the comparison takes as
+// long as one against a real hash.
+func (f *Frontend) dummyPasswordHash() []byte {
+	f.dummyHashOnce.Do(func() {
+		f.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), f.passwordHashCost())
+	})
+	return f.dummyHash
+}
+
+// validatePassword enforces length limits without inspecting content
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return invalidField("password", CodeInvalidLength, fmt.Sprintf("password must be %d-%d bytes", minPasswordLength, maxPasswordLength))
+	}
+	return nil
+}
+
+// validatePasswordHashCost checks a configured bcrypt cost
+func validatePasswordHashCost(cost int) error {
+	if cost != 0 && (cost < bcrypt.MinCost || cost > bcrypt.MaxCost) {
+		return invalidField("PasswordHashCost", CodeOutOfRange, fmt.Sprintf("password hash cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDummyPasswordHashUsesEachFrontendsCost(t *testing.T) {
+	for _, cost := range []int{bcrypt.MinCost, bcrypt.MinCost + 1} {
+		db, _ := newFakeDB(t)
+		config := DefaultConfig()
+		config.PasswordHashCost = cost
+		f, err := NewFrontendWithDB(db, config)
+		if err != nil {
+			t.Fatalf("NewFrontendWithDB: %v", err)
+		}
+
+		got, err := bcrypt.Cost(f.dummyPasswordHash())
+		if err != nil {
+			t.Fatalf("bcrypt.Cost: %v", err)
+		}
+		if got != cost {
+			t.Errorf("dummy hash cost = %d, want the Frontend's %d", got, cost)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Query runs a caller-supplied SELECT on the primary and appends every row
+// to dest, which must be a pointer to a slice. Struct elements are filled by
+// matching result columns to fields tagged `db:"column"`, as in InsertStruct,
+// and every column must have a matching field. A NULL leaves a field that
+// cannot hold it, such as a string or time.Time, at its zero value. Any other
+// element type, such as int64 or string, requires a single-column result.
+//
+// The query text is sent as written, with $N placeholders rebound for the
+// configured driver. Callers own its injection safety: never build it from
+// user input, and pass every value through args. Errors and the timeout are
+// handled like the built-in methods.
+func (f *Frontend) Query(ctx context.Context, dest any, query string, args ...any) error {
+	return f.instrument(ctx, "Query", func(ctx context.Context) error {
+		_, err := cancellable(ctx, f, f.primary(), func(q querier) (struct{}, error) {
+			return struct{}{}, f.queryInto(ctx, q, dest, query, args...)
+		})
+		return err
+	})
+}
+
+// Query runs a caller-supplied SELECT within the transaction
+func (t *Tx) Query(ctx context.Context, dest any, query string, args ...any) error {
+	return t.f.queryInto(ctx, t.tx, dest, query, args...)
+}
+
+// RowQuerier runs ad-hoc queries; both Frontend and Tx satisfy it, so
+// QueryRows works inside and outside a transaction
+type RowQuerier interface {
+	Query(ctx context.Context, dest any, query string, args ...any) error
+}
+
+var (
+	_ RowQuerier = (*Frontend)(nil)
+	_ RowQuerier = (*Tx)(nil)
+)
+
+// QueryRows runs a caller-supplied SELECT through q's Query and returns the
+// rows as a []T, so read models of other tables need no destination slice:
+//
+//	orders, err := db.QueryRows[Order](ctx, frontend, "SELECT id, total FROM orders WHERE user_id = $1", userID)
+//
+// T follows the Query rules, normally a struct with `db:"column"` tags. The
+// same caution applies to the query text: values go through args only. An
+// empty result is an empty slice, not an error.
+func QueryRows[T any](ctx context.Context, q RowQuerier, query string, args ...any) ([]T, error) {
+	rows := make([]T, 0)
+	if err := q.Query(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Exec runs a caller-supplied statement on the primary and returns the number
+// of rows affected. As with Query, callers own the injection safety of the
+// query text and must pass every value through args.
+func (f *Frontend) Exec(ctx context.Context, query string, args ...any) (int64, error) {
+	return instrumentResult(ctx, f, "Exec", func(ctx context.Context) (int64, error) {
+		return f.execQuery(ctx, f.primary(), query, args...)
+	})
+}
+
+// Exec runs a caller-supplied statement within the transaction
+func (t *Tx) Exec(ctx context.Context, query string, args ...any) (int64, error) {
+	return t.f.execQuery(ctx, t.tx, query, args...)
+}
+
+// execQuery runs an ad-hoc statement and reports the rows affected
+func (f *Frontend) execQuery(ctx context.Context, q querier, query string, args ...any) (int64, error) {
+	if strings.TrimSpace(query) == "" {
+		return 0, fmt.Errorf("%w: query is required", ErrInvalidInput)
+	}
+
+	result, err := f.execCounted(ctx, q, query, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, duplicateError()
+		}
+		return 0, databaseError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, databaseError(err)
+	}
+	return affected, nil
+}
+
+// queryInto runs an ad-hoc query and scans the rows into dest
+func (f *Frontend) queryInto(ctx context.Context, q querier, dest any, query string, args ...any) error {
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("%w: query is required", ErrInvalidInput)
+	}
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: Query requires a pointer to a slice", ErrInvalidInput)
+	}
+	slice = slice.Elem()
+
+	rows, err := f.query(ctx, q, query, args...)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return databaseError(err)
+	}
+	elemType := slice.Type().Elem()
+	targets, zeroOnNull, err := scanTargets(elemType, columns)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		elem, dests := targets()
+		if err := scanRow(rows, dests, zeroOnNull); err != nil {
+			return databaseError(err)
+		}
+		slice.Set(reflect.Append(slice, elem))
+		countRows(ctx, 1)
+	}
+	if err := rows.Err(); err != nil {
+		return databaseError(err)
+	}
+	return nil
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// scanTargets checks that elemType can hold rows with the given columns and
+// returns a function producing a fresh element and the Scan destinations
+// that fill it. zeroOnNull marks the struct fields, such as a string or
+// time.Time, that cannot hold NULL and are left at their zero value instead.
+func scanTargets(elemType reflect.Type, columns []string) (targets func() (reflect.Value, []any), zeroOnNull []bool, err error) {
+	structType, isPointer := elemType, false
+	if structType.Kind() == reflect.Pointer {
+		structType, isPointer = structType.Elem(), true
+	}
+
+	// Scanners and time.Time are structs but scan as a single value
+	if structType.Kind() != reflect.Struct || structType == timeType ||
+		reflect.PointerTo(structType).Implements(scannerType) {
+		if len(columns) != 1 {
+			return nil, nil, fmt.Errorf("%w: query returns %d columns for a non-struct slice", ErrInvalidInput, len(columns))
+		}
+		return func() (reflect.Value, []any) {
+			ptr := reflect.New(elemType)
+			return ptr.Elem(), []any{ptr.Interface()}
+		}, nil, nil
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+	paths := make([][]int, len(columns))
+	zeroOnNull = make([]bool, len(columns))
+	for i, column := range columns {
+		path, ok := fields[column]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: no db-tagged field for column %s", ErrInvalidInput, column)
+		}
+		paths[i] = path
+		fieldType := structType.FieldByIndex(path).Type
+		zeroOnNull[i] = fieldType.Kind() != reflect.Pointer && fieldType.Kind() != reflect.Slice &&
+			!reflect.PointerTo(fieldType).Implements(scannerType)
+	}
+
+	return func() (reflect.Value, []any) {
+		ptr := reflect.New(structType)
+		dests := make([]any, len(paths))
+		for i, path := range paths {
+			dests[i] = ptr.Elem().FieldByIndex(path).Addr().Interface()
+		}
+		if isPointer {
+			return ptr, dests
+		}
+		return ptr.Elem(), dests
+	}, zeroOnNull, nil
+}
+
+// nullProbe records whether a column is NULL without converting it
+type nullProbe bool
+
+// Scan implements sql.Scanner
+func (p *nullProbe) Scan(src any) error {
+	*p = src == nil
+	return nil
+}
+
+// scanRow scans the current row into dests. Columns marked in zeroOnNull
+// that are NULL are skipped, leaving the fresh field at its zero value, so
+// nullable columns such as Schema.UpdatedAtColumn scan into plain fields.
+// The row is scanned twice in that case, which database/sql allows.
+func scanRow(rows *sql.Rows, dests []any, zeroOnNull []bool) error {
+	if !slices.Contains(zeroOnNull, true) {
+		return rows.Scan(dests...)
+	}
+	nulls := make([]nullProbe, len(dests))
+	probes := make([]any, len(dests))
+	for i := range nulls {
+		probes[i] = &nulls[i]
+	}
+	if err := rows.Scan(probes...); err != nil {
+		return err
+	}
+	for i, isNull := range nulls {
+		if bool(isNull) && zeroOnNull[i] {
+			dests[i] = new(any)
+		}
+	}
+	return rows.Scan(dests...)
+}
+
+// structFields maps each db tag name in t to its field index path, walking
+// untagged embedded structs the same way structColumns does. The first field
+// with a given name wins.
+func structFields(t reflect.Type, prefix []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := append(append([]int(nil), prefix...), i)
+		tag, hasTag := field.Tag.Lookup("db")
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			structFields(field.Type, path, fields)
+			continue
+		}
+		if !field.IsExported() || !hasTag || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if _, seen := fields[name]; !seen {
+			fields[name] = path
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestQueryScansNullUUIDIntoUser(t *testing.T) {
+	db, store := newFakeDB(t)
+	config := DefaultConfig()
+	config.Schema.UUIDColumn = "uuid"
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	const id = "6f1c2a4e-8b3d-4c5e-9f7a-1b2c3d4e5f60"
+	store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com", "created_at": time.Now()})
+	store.seed(map[string]driver.Value{"username": "bob", "email": "bob@example.com", "created_at": time.Now(), "uuid": id})
+
+	users, err := QueryRows[User](context.Background(), f, `SELECT id, username, uuid FROM users`)
+	if err != nil {
+		t.Fatalf("QueryRows: %v", err)
+	}
+	if len(users) != 2 || users[0].UUID != "" || users[1].UUID != id {
+		t.Errorf("QueryRows = %+v, want alice without a UUID and bob with %s", users, id)
+	}
+}
+
+func TestQueryNullIntoNonStructFails(t *testing.T) {
+	db, store := newFakeDB(t)
+	f, err := NewFrontendWithDB(db, DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com"})
+
+	// Only struct fields are zeroed; a bare string has no field to leave empty
+	if _, err := QueryRows[string](context.Background(), f, `SELECT uuid FROM users`); err == nil {
+		t.Error("QueryRows[string] over NULL = nil, want an error")
+	}
+}
+
+func TestQueryScansNullUpdatedAtIntoUser(t *testing.T) {
+	db, store := newFakeDB(t)
+	config := DefaultConfig()
+	config.TrackUpdatedAt = true
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com", "created_at": updated})
+	store.seed(map[string]driver.Value{"username": "bob", "email": "bob@example.com", "created_at": updated, "updated_at": updated})
+
+	users, err := QueryRows[User](context.Background(), f, `SELECT id, username, email, created_at, updated_at FROM users`)
+	if err != nil {
+		t.Fatalf("QueryRows: %v", err)
+	}
+	if len(users) != 2 || !users[0].UpdatedAt.IsZero() || !users[1].UpdatedAt.Equal(updated) {
+		t.Errorf("QueryRows = %+v, want alice never updated and bob updated at %v", users, updated)
+	}
+}
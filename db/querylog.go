@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// logStatement writes the statement about to be sent to Config.QueryLogger at
+// debug level. Only the query text and the number of arguments are logged,
+// never their values. The text is this package's own SQL built from schema
+// identifiers, so it contains no caller input.
+func (f *Frontend) logStatement(ctx context.Context, query string, args []any) {
+	logger := f.config.QueryLogger
+	if logger == nil || !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "db query", append(f.logAttrs(ctx),
+		slog.String("query", query),
+		slog.Int("args", len(args)),
+	)...)
+}
+
+// logOperation writes a failed operation to Config.QueryLogger. Failures
+// caused by the caller, such as invalid input or a missing user, are routine
+// and logged at debug level; everything else is logged as an error.
+func (f *Frontend) logOperation(ctx context.Context, op string, duration time.Duration, err error) {
+	logger := f.config.QueryLogger
+	if logger == nil || err == nil {
+		return
+	}
+	level := slog.LevelError
+	if errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrNotFound) {
+		level = slog.LevelDebug
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	// Errors returned by this package are already sanitized
+	logger.LogAttrs(ctx, level, "db operation failed", append(f.logAttrs(ctx),
+		slog.Duration("duration", duration),
+		slog.String("error", err.Error()),
+	)...)
+}
+
+// logAttrs returns the attributes shared by every query log record
+func (f *Frontend) logAttrs(ctx context.Context) []slog.Attr {
+	attrs := []slog.Attr{slog.String("op", opFromContext(ctx))}
+	if id := f.requestID(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	return attrs
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: operations are admitted at Rate per second on
+// average, with bursts of up to Burst operations
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig limits how often operations may start, protecting the pool
+// from traffic spikes. Limits apply to Frontend methods; operations inside a
+// Tx count once, as ExecuteInTransaction.
+type RateLimitConfig struct {
+	// Global applies to every operation; nil leaves them unlimited
+	Global *RateLimit
+	// Operations sets limits for individual methods by name, e.g.
+	// "SearchUsers". An operation must pass both its own and the global limit.
+	Operations map[string]RateLimit
+	// Wait makes over-limit calls block until a token is available or ctx is
+	// done, instead of failing immediately with ErrRateLimited
+	Wait bool
+}
+
+// rateLimiter holds the buckets built from a RateLimitConfig. A nil
+// *rateLimiter admits everything.
+type rateLimiter struct {
+	global     *tokenBucket
+	operations map[string]*tokenBucket
+	wait       bool
+	// now is the clock buckets refill against; tests replace it
+	now func() time.Time
+}
+
+// newRateLimiter builds the limiter for config, or nil when unconfigured
+func newRateLimiter(config *RateLimitConfig) *rateLimiter {
+	if config == nil || (config.Global == nil && len(config.Operations) == 0) {
+		return nil
+	}
+	l := &rateLimiter{
+		operations: make(map[string]*tokenBucket, len(config.Operations)),
+		wait:       config.Wait,
+		now:        time.Now,
+	}
+	if config.Global != nil {
+		l.global = newTokenBucket(*config.Global, l.now())
+	}
+	for op, limit := range config.Operations {
+		l.operations[op] = newTokenBucket(limit, l.now())
+	}
+	return l
+}
+
+// admit takes a token for op from its bucket and the global bucket. In wait
+// mode it sleeps until both are available or ctx is done; a token is never
+// consumed by a call that ends up rejected.
+func (l *rateLimiter) admit(ctx context.Context, op string) error {
+	if l == nil {
+		return nil
+	}
+
+	buckets := make([]*tokenBucket, 0, 2)
+	if b := l.operations[op]; b != nil {
+		buckets = append(buckets, b)
+	}
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+
+	now := l.now()
+	var delay time.Duration
+	for i, b := range buckets {
+		d, ok := b.reserve(now, l.wait)
+		if !ok {
+			for _, taken := range buckets[:i] {
+				taken.refund()
+			}
+			return fmt.Errorf("%w: %s", ErrRateLimited, op)
+		}
+		delay = max(delay, d)
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	// Fail fast when the deadline would expire before a token is free
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay {
+		for _, b := range buckets {
+			b.refund()
+		}
+		return fmt.Errorf("%w: %s", ErrRateLimited, op)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, b := range buckets {
+			b.refund()
+		}
+		return fmt.Errorf("%w: %s: %w", ErrRateLimited, op, ctx.Err())
+	}
+}
+
+// tokenBucket is a mutex-guarded token bucket refilled lazily on each call
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket for limit, last refilled at now
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  float64(limit.Burst),
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+// reserve takes one token. Without wait it fails when none is available;
+// with wait it always succeeds, letting the balance go negative, and returns
+// how long the caller must sleep before using the token.
+func (b *tokenBucket) reserve(now time.Time, wait bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if !wait {
+		return 0, false
+	}
+
+	b.tokens--
+	return time.Duration(-b.tokens / b.rate * float64(time.Second)), true
+}
+
+// refund returns a token taken by a call that did not go ahead
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// validateRateLimits rejects limits that could never admit an operation
+func validateRateLimits(config *RateLimitConfig) error {
+	if config == nil {
+		return nil
+	}
+	check := func(limit RateLimit) error {
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return invalidField("RateLimits", CodeOutOfRange, "rate limits need a positive rate and a burst of at least 1")
+		}
+		return nil
+	}
+	if config.Global != nil {
+		if err := check(*config.Global); err != nil {
+			return err
+		}
+	}
+	for _, limit := range config.Operations {
+		if err := check(limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
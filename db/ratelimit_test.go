@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for rateLimiter.now
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestRateLimiter builds the limiter for config on a fake clock starting
+// at the current time, so context deadlines still line up with it
+func newTestRateLimiter(t *testing.T, config *RateLimitConfig) (*rateLimiter, *fakeClock) {
+	t.Helper()
+	l := newRateLimiter(config)
+	if l == nil {
+		t.Fatal("newRateLimiter returned nil")
+	}
+	clock := &fakeClock{t: time.Now()}
+	l.now = clock.now
+	for _, b := range slices.AppendSeq([]*tokenBucket{l.global}, maps.Values(l.operations)) {
+		if b != nil {
+			b.last = clock.t
+		}
+	}
+	return l, clock
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		refund    bool
+		wait      bool
+		wantDelay time.Duration
+		wantOK    bool
+	}
+	tests := []struct {
+		name  string
+		limit RateLimit
+		steps []step
+	}{
+		{
+			name:  "burst then reject",
+			limit: RateLimit{Rate: 2, Burst: 2},
+			steps: []step{
+				{wantOK: true},
+				{wantOK: true},
+				{wantOK: false},
+			},
+		},
+		{
+			name:  "refill at rate",
+			limit: RateLimit{Rate: 2, Burst: 2},
+			steps: []step{
+				{wantOK: true},
+				{wantOK: true},
+				{advance: 250 * time.Millisecond, wantOK: false},
+				{advance: 250 * time.Millisecond, wantOK: true},
+				{wantOK: false},
+			},
+		},
+		{
+			name:  "refill caps at burst",
+			limit: RateLimit{Rate: 2, Burst: 2},
+			steps: []step{
+				{advance: time.Minute, wantOK: true},
+				{wantOK: true},
+				{wantOK: false},
+			},
+		},
+		{
+			name:  "wait goes negative",
+			limit: RateLimit{Rate: 2, Burst: 1},
+			steps: []step{
+				{wait: true, wantOK: true},
+				{wait: true, wantDelay: 500 * time.Millisecond, wantOK: true},
+				{wait: true, wantDelay: time.Second, wantOK: true},
+				// The balance is -2, so a full second only brings it to 0
+				{advance: time.Second, wantOK: false},
+			},
+		},
+		{
+			name:  "refund restores a token",
+			limit: RateLimit{Rate: 2, Burst: 1},
+			steps: []step{
+				{wantOK: true},
+				{refund: true, wantOK: true},
+				{wantOK: false},
+			},
+		},
+		{
+			name:  "refund caps at burst",
+			limit: RateLimit{Rate: 2, Burst: 1},
+			steps: []step{
+				{refund: true, wantOK: true},
+				{wantOK: false},
+			},
+		},
+		{
+			name:  "refund repays a negative balance",
+			limit: RateLimit{Rate: 2, Burst: 1},
+			steps: []step{
+				{wait: true, wantOK: true},
+				{wait: true, wantDelay: 500 * time.Millisecond, wantOK: true},
+				{refund: true, wait: true, wantDelay: 500 * time.Millisecond, wantOK: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			b := newTokenBucket(tt.limit, now)
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				if s.refund {
+					b.refund()
+				}
+				delay, ok := b.reserve(now, s.wait)
+				if delay != s.wantDelay || ok != s.wantOK {
+					t.Fatalf("step %d: reserve = (%v, %v), want (%v, %v)", i, delay, ok, s.wantDelay, s.wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterRejectionRefundsEarlierBuckets(t *testing.T) {
+	l, _ := newTestRateLimiter(t, &RateLimitConfig{
+		Global:     &RateLimit{Rate: 1, Burst: 1},
+		Operations: map[string]RateLimit{"GetUserByID": {Rate: 1, Burst: 2}},
+	})
+	ctx := context.Background()
+
+	if err := l.admit(ctx, "GetUserByID"); err != nil {
+		t.Fatalf("first admit = %v, want nil", err)
+	}
+	// The operation bucket has a token left, but the global one is empty
+	if err := l.admit(ctx, "GetUserByID"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second admit = %v, want ErrRateLimited", err)
+	}
+	if got := l.operations["GetUserByID"].tokens; got != 1 {
+		t.Errorf("operation bucket has %v tokens after the rejection, want 1", got)
+	}
+}
+
+func TestRateLimiterAdmitsAfterRefill(t *testing.T) {
+	l, clock := newTestRateLimiter(t, &RateLimitConfig{Global: &RateLimit{Rate: 10, Burst: 1}})
+	ctx := context.Background()
+
+	if err := l.admit(ctx, "CreateUser"); err != nil {
+		t.Fatalf("first admit = %v, want nil", err)
+	}
+	if err := l.admit(ctx, "CreateUser"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("admit on an empty bucket = %v, want ErrRateLimited", err)
+	}
+	clock.advance(100 * time.Millisecond)
+	if err := l.admit(ctx, "CreateUser"); err != nil {
+		t.Errorf("admit after refill = %v, want nil", err)
+	}
+}
+
+func TestRateLimiterWaitFailsFastPastDeadline(t *testing.T) {
+	l, clock := newTestRateLimiter(t, &RateLimitConfig{Global: &RateLimit{Rate: 1, Burst: 1}, Wait: true})
+	if err := l.admit(context.Background(), "CreateUser"); err != nil {
+		t.Fatalf("first admit = %v, want nil", err)
+	}
+
+	// The next token is a second away, past the deadline
+	ctx, cancel := context.WithDeadline(context.Background(), clock.t.Add(100*time.Millisecond))
+	defer cancel()
+	start := time.Now()
+	err := l.admit(ctx, "CreateUser")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("admit = %v, want ErrRateLimited", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("admit = %v, want it to fail before the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("admit took %v, want it to return without sleeping", elapsed)
+	}
+	if l.global.tokens != 0 {
+		t.Errorf("bucket has %v tokens, want the reservation refunded to 0", l.global.tokens)
+	}
+}
+
+func TestRateLimiterWaitRespectsCancellation(t *testing.T) {
+	l, _ := newTestRateLimiter(t, &RateLimitConfig{Global: &RateLimit{Rate: 0.001, Burst: 1}, Wait: true})
+	if err := l.admit(context.Background(), "CreateUser"); err != nil {
+		t.Fatalf("first admit = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := l.admit(ctx, "CreateUser")
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("admit = %v, want ErrRateLimited wrapping context.Canceled", err)
+	}
+	if l.global.tokens != 0 {
+		t.Errorf("bucket has %v tokens, want the reservation refunded to 0", l.global.tokens)
+	}
+}
+
+func TestRateLimiterWaitSleepsForToken(t *testing.T) {
+	l, _ := newTestRateLimiter(t, &RateLimitConfig{Global: &RateLimit{Rate: 1000, Burst: 1}, Wait: true})
+	ctx := context.Background()
+	for i := range 3 {
+		if err := l.admit(ctx, "CreateUser"); err != nil {
+			t.Fatalf("admit %d = %v, want nil", i, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// primary returns the current primary pool
+func (f *Frontend) primary() *sql.DB {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.db
+}
+
+// pools returns the current primary and replica pools; replica may be nil
+func (f *Frontend) pools() (primary, replica *sql.DB) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.db, f.replica
+}
+
+// Reconnect checks the primary and replica pools and replaces any that fail
+// the check with a freshly opened pool, for example after a database
+// failover left the old pool holding stale connections. It returns nil
+// without reopening anything when both pools are healthy.
+//
+// Reconnect is safe to call concurrently with queries: calls that already
+// hold a connection from a replaced pool finish on it before the old pool is
+// closed, and later calls use the new pool. Pools supplied to
+// NewFrontendWithDB cannot be reopened, so Reconnect only reports their
+// health.
+func (f *Frontend) Reconnect(ctx context.Context) error {
+	f.reconnectMu.Lock()
+	defer f.reconnectMu.Unlock()
+
+	checkCtx, cancel := context.WithTimeout(ctx, f.config.connectTimeout())
+	defer cancel()
+
+	oldPrimary, oldReplica := f.pools()
+	primaryErr := f.checkDB(checkCtx, oldPrimary)
+	var replicaErr error
+	if oldReplica != nil {
+		replicaErr = f.checkDB(checkCtx, oldReplica)
+	}
+	if primaryErr == nil && replicaErr == nil {
+		return nil
+	}
+
+	if f.reopen == nil {
+		if primaryErr != nil {
+			return primaryErr
+		}
+		return fmt.Errorf("read replica: %w", replicaErr)
+	}
+
+	var newPrimary, newReplica *sql.DB
+	var primaryStmts, replicaStmts stmtCache
+	var err error
+	if primaryErr != nil {
+		if newPrimary, err = f.reopen(ctx, false); err != nil {
+			return err
+		}
+		if primaryStmts, err = f.preparePrimary(ctx, newPrimary); err != nil {
+			newPrimary.Close()
+			return err
+		}
+	}
+	if replicaErr != nil {
+		if newReplica, err = f.reopen(ctx, true); err == nil {
+			if replicaStmts, err = f.prepareReplica(ctx, newReplica); err != nil {
+				newReplica.Close()
+			}
+		}
+		if err != nil {
+			if newPrimary != nil {
+				primaryStmts.close()
+				newPrimary.Close()
+			}
+			return fmt.Errorf("read replica: %w", err)
+		}
+	}
+
+	// Swap in the new pools, then close the old ones outside the lock so
+	// in-flight queries can drain without blocking new callers
+	f.mu.Lock()
+	var stale []*sql.DB
+	var staleStmts []stmtCache
+	if newPrimary != nil {
+		stale = append(stale, f.db)
+		staleStmts = append(staleStmts, f.stmts)
+		f.db, f.stmts = newPrimary, primaryStmts
+	}
+	if newReplica != nil {
+		stale = append(stale, f.replica)
+		staleStmts = append(staleStmts, f.replicaStmts)
+		f.replica, f.replicaStmts = newReplica, replicaStmts
+	}
+	f.mu.Unlock()
+
+	for _, cache := range staleStmts {
+		cache.close()
+	}
+	for _, db := range stale {
+		db.Close()
+	}
+
+	f.logf("reconnected to database")
+	return nil
+}
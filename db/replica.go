@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReadConfig describes a read-only replica. Empty fields inherit the primary's
+// values, and the replica authenticates with the primary's credentials and
+// shares its pool and SSL settings.
+type ReadConfig struct {
+	Host     string
+	Port     int
+	Database string
+
+	// MaxLag, when positive, sends reads to the primary while the replica's
+	// lag (see Frontend.ReplicaLag) exceeds it or cannot be measured.
+	// PostgreSQL only.
+	MaxLag time.Duration
+	// LagCheckInterval is how often the lag is measured when MaxLag is set;
+	// zero means every second
+	LagCheckInterval time.Duration
+}
+
+// replicaConfig returns a copy of c pointed at the read replica
+func (c *Config) replicaConfig() *Config {
+	rc := *c
+	if c.ReadReplica.Host != "" {
+		rc.Host = c.ReadReplica.Host
+	}
+	if c.ReadReplica.Port != 0 {
+		rc.Port = c.ReadReplica.Port
+	}
+	if c.ReadReplica.Database != "" {
+		rc.Database = c.ReadReplica.Database
+	}
+	rc.ReadReplica = nil
+	return &rc
+}
+
+// reader returns the pool used for read-only queries: the replica when one is
+// configured and not lagging, otherwise the primary
+func (f *Frontend) reader() *sql.DB {
+	primary, replica := f.pools()
+	if replica != nil && !f.replicaLagging() {
+		return replica
+	}
+	return primary
+}
+
+// validateReadReplica checks the replica's lag settings
+func validateReadReplica(config *Config) error {
+	rc := config.ReadReplica
+	if rc == nil {
+		return nil
+	}
+	if rc.MaxLag < 0 {
+		return invalidField("ReadReplica.MaxLag", CodeOutOfRange, "replica lag settings must not be negative")
+	}
+	if rc.LagCheckInterval < 0 {
+		return invalidField("ReadReplica.LagCheckInterval", CodeOutOfRange, "replica lag settings must not be negative")
+	}
+	if rc.MaxLag > 0 && config.driver() != DriverPostgres {
+		return invalidField("ReadReplica.MaxLag", CodeUnsupported, "replica lag checks require postgres")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// requestIDKey is the context key used by WithRequestID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id, such as an HTTP request or
+// trace ID, so that log lines and observer events for operations made with
+// it can be correlated with the originating request. Like the actor, it
+// never reaches SQL.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID set by WithRequestID, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// requestID returns the request ID for ctx: the value under
+// Config.RequestIDKey when one is configured, otherwise the value set by
+// WithRequestID. It returns "" when there is none.
+func (f *Frontend) requestID(ctx context.Context) string {
+	if f.config.RequestIDKey == nil {
+		id, _ := RequestIDFromContext(ctx)
+		return id
+	}
+	return contextString(ctx, f.config.RequestIDKey)
+}
+
+// contextString formats the value under key as a string. Values may be
+// strings, ints, int64s or fmt.Stringers; anything else is "".
+func contextString(ctx context.Context, key any) string {
+	switch v := ctx.Value(key).(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return ""
+	}
+}
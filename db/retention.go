@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeleteUsersOlderThan deletes every user created before cutoff in a single
+// statement and returns how many were deleted. With Config.SoftDelete they
+// are soft-deleted instead, and users already deleted are not counted. Run
+// CountUsersOlderThan first to see what a cleanup job would remove.
+func (f *Frontend) DeleteUsersOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return instrumentResult(ctx, f, "DeleteUsersOlderThan", func(ctx context.Context) (int64, error) {
+		return auditWrite(ctx, f, "DeleteUsersOlderThan", func(q querier) (int64, []int64, error) {
+			return f.deleteUsersOlderThan(ctx, q, cutoff)
+		})
+	})
+}
+
+// DeleteUsersOlderThan deletes users created before cutoff within the
+// transaction
+func (t *Tx) DeleteUsersOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return txAuditWrite(ctx, t, "DeleteUsersOlderThan", func(q querier) (int64, []int64, error) {
+		return t.f.deleteUsersOlderThan(ctx, q, cutoff)
+	})
+}
+
+// CountUsersOlderThan is a dry run of DeleteUsersOlderThan: it returns the
+// number of users that would be deleted for cutoff without deleting them. It
+// reads from the primary so the count matches what a delete would see.
+func (f *Frontend) CountUsersOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return instrumentResult(ctx, f, "CountUsersOlderThan", func(ctx context.Context) (int64, error) {
+		return f.countUsersOlderThan(ctx, f.primary(), cutoff)
+	})
+}
+
+// CountUsersOlderThan counts users created before cutoff within the
+// transaction
+func (t *Tx) CountUsersOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return t.f.countUsersOlderThan(ctx, t.tx, cutoff)
+}
+
+// deleteUsersOlderThan deletes users created before cutoff
+func (f *Frontend) deleteUsersOlderThan(ctx context.Context, q querier, cutoff time.Time) (int64, []int64, error) {
+	if cutoff.IsZero() {
+		return 0, nil, fmt.Errorf("%w: cutoff is required", ErrInvalidInput)
+	}
+	return f.deleteMatching(ctx, q, f.schema.CreatedAtColumn+" < $1", []any{cutoff})
+}
+
+// countUsersOlderThan counts the rows deleteUsersOlderThan would affect
+func (f *Frontend) countUsersOlderThan(ctx context.Context, q querier, cutoff time.Time) (int64, error) {
+	if cutoff.IsZero() {
+		return 0, fmt.Errorf("%w: cutoff is required", ErrInvalidInput)
+	}
+
+	s := f.schema
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s%s`, s.Table, f.where(s.CreatedAtColumn+" < $1"))
+
+	var count int64
+	if err := f.queryRow(ctx, q, query, cutoff).Scan(&count); err != nil {
+		return 0, databaseError(err)
+	}
+	return count, nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// defaultTxRetryBackoff is used when Config.TxRetryBackoff is zero
+const defaultTxRetryBackoff = 10 * time.Millisecond
+
+// maxTxRetryBackoff caps the delay between attempts
+const maxTxRetryBackoff = time.Second
+
+// retryTx runs attempt, re-running it after a jittered exponential backoff
+// while it fails with a transaction conflict, as classified by isTxConflict,
+// and retries remain. The last error is returned unchanged.
+func (f *Frontend) retryTx(ctx context.Context, attempt func() error) error {
+	backoff := f.config.TxRetryBackoff
+	if backoff == 0 {
+		backoff = defaultTxRetryBackoff
+	}
+
+	for retry := 0; ; retry++ {
+		err := attempt()
+		if err == nil || retry >= f.config.TxMaxRetries || !isTxConflict(err) {
+			return err
+		}
+
+		delay := backoff
+		for i := 0; i < retry && delay < maxTxRetryBackoff; i++ {
+			delay *= 2
+		}
+		// Jitter keeps conflicting transactions from retrying in lockstep
+		delay = min(delay, maxTxRetryBackoff)
+		delay = delay/2 + rand.N(delay/2+1)
+		f.logfContext(ctx, "retrying transaction after conflict (attempt %d of %d)", retry+1, f.config.TxMaxRetries)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// txKey is the context key under which a Tx stores itself
+type txKey struct{}
+
+// Context returns a context derived from the one the transaction was started
+// with that carries the transaction. Passing it to ExecuteInTransaction or
+// RunInTx on the same Frontend nests the inner call in a savepoint of this
+// transaction instead of starting an independent one.
+func (t *Tx) Context() context.Context {
+	return t.ctx
+}
+
+// activeTx returns the transaction carried by ctx if it belongs to f
+func (f *Frontend) activeTx(ctx context.Context) *Tx {
+	if t, ok := ctx.Value(txKey{}).(*Tx); ok && t.f == f {
+		return t
+	}
+	return nil
+}
+
+// inSavepoint runs fn inside a savepoint of parent. An error or panic in fn
+// rolls back to the savepoint, undoing only fn's work; the outer transaction
+// stays usable and decides whether to commit. Audit events from fn are handed
+// to parent on success, so they are delivered only if the outer transaction
+// commits.
+func (f *Frontend) inSavepoint(ctx context.Context, parent *Tx, fn func(*Tx) error) error {
+	root := parent.root()
+	root.mu.Lock()
+	root.savepoints++
+	name := fmt.Sprintf("sp_%d", root.savepoints)
+	root.mu.Unlock()
+
+	// The name is generated from a counter, never from caller input
+	if _, err := parent.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return databaseError(err)
+	}
+
+	child := &Tx{tx: parent.tx, f: f, parent: parent}
+	child.ctx = context.WithValue(ctx, txKey{}, child)
+
+	rollback := func() {
+		if _, err := parent.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+			f.logfContext(ctx, "rollback to savepoint error: %v", sanitizeError(err))
+		}
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(child); err != nil {
+		rollback()
+		return err
+	}
+
+	if _, err := parent.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return databaseError(err)
+	}
+
+	parent.mu.Lock()
+	parent.audit = append(parent.audit, child.audit...)
+	parent.mu.Unlock()
+	return nil
+}
+
+// root returns the outermost transaction t is nested in
+func (t *Tx) root() *Tx {
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
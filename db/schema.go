@@ -0,0 +1,198 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Schema names the table and columns that hold user records. Empty fields
+// fall back to the defaults from DefaultSchema.
+//
+// Identifiers cannot be bound as query parameters, so every name is checked
+// against a strict allowlist (letters, digits and underscores, starting with
+// a letter or underscore) before it is interpolated into SQL. The table may
+// be schema-qualified, e.g. "auth.app_users".
+type Schema struct {
+	Table           string
+	IDColumn        string
+	UsernameColumn  string
+	EmailColumn     string
+	CreatedAtColumn string
+	// DeletedAtColumn is only used when Config.SoftDelete is enabled
+	DeletedAtColumn string
+	// PasswordHashColumn holds bcrypt hashes written by CreateUserWithPassword
+	PasswordHashColumn string
+	// VersionColumn is only used when Config.OptimisticLocking is enabled
+	VersionColumn string
+	// UUIDColumn, when set, holds a UUID identifying each user alongside the
+	// integer ID. It is read into User.UUID and enables GetUserByUUID. It
+	// has no default and is unused when empty.
+	UUIDColumn string
+	// UpdatedAtColumn is only used when Config.TrackUpdatedAt is enabled
+	UpdatedAtColumn string
+	// MetadataColumn, when set, holds a JSON object of profile data, such as
+	// a PostgreSQL jsonb column. It is read into User.Metadata and written by
+	// UpdateUserMetadata. It has no default and is unused when empty.
+	MetadataColumn string
+	// TenantColumn, when set, scopes every built-in read and write to the
+	// tenant attached with WithTenant: it is matched in every WHERE clause
+	// and written by every insert, so a user of another tenant is
+	// ErrNotFound. It has no default and is unused when empty. Migrate
+	// adds it and makes the unique indexes per tenant, except on SQLite.
+	TenantColumn string
+	// EmailVerifiedColumn, VerificationTokenColumn and
+	// VerificationSentAtColumn are only used when Config.EmailVerification
+	// is enabled. They hold the verified flag, the SHA-256 hash of the
+	// pending token and when that token was issued.
+	EmailVerifiedColumn      string
+	VerificationTokenColumn  string
+	VerificationSentAtColumn string
+}
+
+// DefaultSchema returns the table layout used when no overrides are configured
+func DefaultSchema() Schema {
+	return Schema{
+		Table:              "users",
+		IDColumn:           "id",
+		UsernameColumn:     "username",
+		EmailColumn:        "email",
+		CreatedAtColumn:    "created_at",
+		DeletedAtColumn:    "deleted_at",
+		PasswordHashColumn: "password_hash",
+		VersionColumn:      "version",
+		UpdatedAtColumn:    "updated_at",
+
+		EmailVerifiedColumn:      "email_verified",
+		VerificationTokenColumn:  "verification_token",
+		VerificationSentAtColumn: "verification_sent_at",
+	}
+}
+
+var (
+	identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+	tableNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}(\.[a-zA-Z_][a-zA-Z0-9_]{0,62})?$`)
+)
+
+// withDefaults fills empty fields from DefaultSchema
+func (s Schema) withDefaults() Schema {
+	defaults := DefaultSchema()
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	fill(&s.Table, defaults.Table)
+	fill(&s.IDColumn, defaults.IDColumn)
+	fill(&s.UsernameColumn, defaults.UsernameColumn)
+	fill(&s.EmailColumn, defaults.EmailColumn)
+	fill(&s.CreatedAtColumn, defaults.CreatedAtColumn)
+	fill(&s.DeletedAtColumn, defaults.DeletedAtColumn)
+	fill(&s.PasswordHashColumn, defaults.PasswordHashColumn)
+	fill(&s.VersionColumn, defaults.VersionColumn)
+	fill(&s.UpdatedAtColumn, defaults.UpdatedAtColumn)
+	fill(&s.EmailVerifiedColumn, defaults.EmailVerifiedColumn)
+	fill(&s.VerificationTokenColumn, defaults.VerificationTokenColumn)
+	fill(&s.VerificationSentAtColumn, defaults.VerificationSentAtColumn)
+	return s
+}
+
+// validateSchema checks every identifier against the allowlist
+func validateSchema(s Schema) error {
+	s = s.withDefaults()
+	if !tableNamePattern.MatchString(s.Table) {
+		return invalidField("Schema.Table", CodeInvalidFormat, "invalid table name")
+	}
+	columns := []struct{ field, name string }{
+		{"IDColumn", s.IDColumn},
+		{"UsernameColumn", s.UsernameColumn},
+		{"EmailColumn", s.EmailColumn},
+		{"CreatedAtColumn", s.CreatedAtColumn},
+		{"DeletedAtColumn", s.DeletedAtColumn},
+		{"PasswordHashColumn", s.PasswordHashColumn},
+		{"VersionColumn", s.VersionColumn},
+		{"UpdatedAtColumn", s.UpdatedAtColumn},
+		{"EmailVerifiedColumn", s.EmailVerifiedColumn},
+		{"VerificationTokenColumn", s.VerificationTokenColumn},
+		{"VerificationSentAtColumn", s.VerificationSentAtColumn},
+	}
+	optional := []struct{ field, name string }{
+		{"UUIDColumn", s.UUIDColumn},
+		{"TenantColumn", s.TenantColumn},
+		{"MetadataColumn", s.MetadataColumn},
+	}
+	for _, column := range optional {
+		if column.name != "" {
+			columns = append(columns, column)
+		}
+	}
+	for _, column := range columns {
+		if !identifierPattern.MatchString(column.name) {
+			return invalidField("Schema."+column.field, CodeInvalidFormat, "invalid column name")
+		}
+	}
+	return nil
+}
+
+// userColumns returns the select list matching the scan order of scanUser
+func (f *Frontend) userColumns() string {
+	s := f.schema
+	columns := []string{s.IDColumn, s.UsernameColumn, s.EmailColumn, s.CreatedAtColumn}
+	if f.config.OptimisticLocking {
+		columns = append(columns, s.VersionColumn)
+	}
+	if s.UUIDColumn != "" {
+		columns = append(columns, s.UUIDColumn)
+	}
+	if f.config.TrackUpdatedAt {
+		columns = append(columns, s.UpdatedAtColumn)
+	}
+	if f.config.EmailVerification {
+		columns = append(columns, s.EmailVerifiedColumn)
+	}
+	if s.MetadataColumn != "" {
+		columns = append(columns, s.MetadataColumn)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// versionBump returns the SET fragment that increments the version column,
+// or "" when optimistic locking is disabled
+func (f *Frontend) versionBump() string {
+	if !f.config.OptimisticLocking {
+		return ""
+	}
+	return fmt.Sprintf(", %[1]s = %[1]s + 1", f.schema.VersionColumn)
+}
+
+// touchUpdatedAt returns the SET fragment that stamps the updated-at column,
+// or "" when Config.TrackUpdatedAt is disabled. CURRENT_TIMESTAMP is the
+// spelling every supported driver accepts.
+func (f *Frontend) touchUpdatedAt() string {
+	if !f.config.TrackUpdatedAt {
+		return ""
+	}
+	return fmt.Sprintf(", %s = CURRENT_TIMESTAMP", f.schema.UpdatedAtColumn)
+}
+
+// where joins conditions with AND into a WHERE clause, adding the soft-delete
+// filter when enabled. It returns "" when there is nothing to filter on.
+// Conditions must be built from validated identifiers and placeholders only.
+func (f *Frontend) where(conditions ...string) string {
+	if f.config.SoftDelete {
+		conditions = append(conditions, f.schema.DeletedAtColumn+" IS NULL")
+	}
+	return f.scoped(conditions...)
+}
+
+// scoped is where without the soft-delete filter, for statements that must
+// see deleted rows. It still adds the tenant condition.
+func (f *Frontend) scoped(conditions ...string) string {
+	if tenant := f.tenantCondition(); tenant != "" {
+		conditions = append(conditions, tenant)
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
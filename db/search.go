@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchMode selects how search terms are matched against usernames and
+// emails
+type SearchMode int
+
+const (
+	// SearchModeLike matches the term as a substring with LIKE '%term%'. It
+	// works on every driver but cannot use an index.
+	SearchModeLike SearchMode = iota
+	// SearchModeFullText matches whole words with PostgreSQL full-text
+	// search (to_tsvector and plainto_tsquery with the 'simple'
+	// configuration, so names are not stemmed). It needs a matching GIN
+	// index to be fast:
+	//
+	//	CREATE INDEX users_search_idx ON users
+	//	    USING GIN (to_tsvector('simple', username || ' ' || email));
+	//
+	// Only PostgreSQL is supported.
+	SearchModeFullText
+)
+
+// searchFilter validates searchTerm and returns the condition shared by every
+// search, with its arguments bound from $1
+func (f *Frontend) searchFilter(searchTerm string) (string, []any, error) {
+	s := f.schema
+	if f.config.SearchMode == SearchModeFullText {
+		if err := validateSearchTerm(searchTerm, f.config.maxSearchTermLength()); err != nil {
+			return "", nil, err
+		}
+		// Must match the indexed expression exactly for the GIN index to apply
+		match := fmt.Sprintf(`to_tsvector('simple', %s || ' ' || %s) @@ plainto_tsquery('simple', $1)`,
+			s.UsernameColumn, s.EmailColumn)
+		return match, []any{strings.TrimSpace(searchTerm)}, nil
+	}
+
+	searchPattern, err := f.searchPattern(searchTerm)
+	if err != nil {
+		return "", nil, err
+	}
+	escape := f.config.likeEscape()
+	match := fmt.Sprintf(`(%s LIKE $1%s OR %s LIKE $2%s)`, s.UsernameColumn, escape, s.EmailColumn, escape)
+	return match, []any{searchPattern, searchPattern}, nil
+}
+
+// defaultMaxSearchTermLength is used when Config.MaxSearchTermLength is zero
+const defaultMaxSearchTermLength = 100
+
+// maxSearchTermLength returns the configured search term cap or the default
+func (c *Config) maxSearchTermLength() int {
+	if c.MaxSearchTermLength == 0 {
+		return defaultMaxSearchTermLength
+	}
+	return c.MaxSearchTermLength
+}
+
+// validateSearchMode checks the search mode against the configured driver
+func validateSearchMode(config *Config) error {
+	switch config.SearchMode {
+	case SearchModeLike:
+		return nil
+	case SearchModeFullText:
+		if config.driver() != DriverPostgres {
+			return invalidField("SearchMode", CodeUnsupported, "full-text search requires postgres")
+		}
+		return nil
+	default:
+		return invalidField("SearchMode", CodeUnsupported, "unknown search mode")
+	}
+}
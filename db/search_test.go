@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		term, want string
+	}{
+		{"john", "john"},
+		{"50%", `50\%`},
+		{"john_doe", `john\_doe`},
+		{`back\slash`, `back\\slash`},
+		// The backslash is escaped first, so added escapes are not doubled
+		{`\%_`, `\\\%\_`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.term); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFilterEscapesPerDriver(t *testing.T) {
+	const term = `50%_off\now`
+	const pattern = `%50\%\_off\\now%`
+
+	tests := []struct {
+		driver Driver
+		match  string
+	}{
+		{DriverPostgres, `(username LIKE $1 ESCAPE '\' OR email LIKE $2 ESCAPE '\')`},
+		{DriverSQLite, `(username LIKE $1 ESCAPE '\' OR email LIKE $2 ESCAPE '\')`},
+		// Backslash is already MySQL's LIKE escape character
+		{DriverMySQL, `(username LIKE $1 OR email LIKE $2)`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.driver), func(t *testing.T) {
+			db, _ := newFakeDB(t)
+			config := DefaultConfig()
+			config.Driver = tt.driver
+			f, err := NewFrontendWithDB(db, config)
+			if err != nil {
+				t.Fatalf("NewFrontendWithDB: %v", err)
+			}
+
+			match, args, err := f.searchFilter(term)
+			if err != nil {
+				t.Fatalf("searchFilter: %v", err)
+			}
+			if match != tt.match {
+				t.Errorf("match = %s, want %s", match, tt.match)
+			}
+			if len(args) != 2 || args[0] != pattern || args[1] != pattern {
+				t.Errorf("args = %q, want two copies of %q", args, pattern)
+			}
+		})
+	}
+}
+
+func TestSearchPatternWithoutSanitization(t *testing.T) {
+	db, _ := newFakeDB(t)
+	config := DefaultConfig()
+	config.DisableSearchSanitization = true
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	// Wildcards are escaped even when the term is otherwise left alone
+	got, err := f.searchPattern("a;b_%")
+	if err != nil {
+		t.Fatalf("searchPattern: %v", err)
+	}
+	if want := `%a;b\_\%%`; got != want {
+		t.Errorf("searchPattern = %q, want %q", got, want)
+	}
+}
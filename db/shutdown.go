@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// drainState counts in-flight operations. Once closing is set, new
+// operations are refused and the last one to finish closes idle.
+type drainState struct {
+	mu      sync.Mutex
+	active  int
+	closing bool
+	idle    chan struct{}
+}
+
+// Shutdown stops accepting new operations, waits for those in flight to
+// finish and then closes the pools like Close. Operations started after
+// Shutdown return ErrShuttingDown, except those running inside a transaction
+// that is already in flight (through Tx.Context), so open transactions can
+// complete. If ctx ends first the pools are closed anyway, aborting whatever
+// is still running, and ErrTimeout is returned.
+//
+// Call it on SIGTERM, after the server has stopped taking requests.
+func (f *Frontend) Shutdown(ctx context.Context) error {
+	d := &f.drain
+	d.mu.Lock()
+	if !d.closing {
+		d.closing = true
+		d.idle = make(chan struct{})
+		if d.active == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	var waitErr error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		waitErr = fmt.Errorf("%w: shutdown: %w", ErrTimeout, ctx.Err())
+	}
+
+	if err := f.Close(); err != nil && waitErr == nil {
+		return err
+	}
+	return waitErr
+}
+
+// enter registers an operation as in flight, or refuses it once Shutdown has
+// begun. Calls inside a transaction of this Frontend are let through while
+// it is still in flight so the transaction can finish.
+func (f *Frontend) enter(ctx context.Context) error {
+	d := &f.drain
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closing && (d.active == 0 || f.activeTx(ctx) == nil) {
+		return ErrShuttingDown
+	}
+	d.active++
+	return nil
+}
+
+// leave marks an operation admitted by enter as finished
+func (f *Frontend) leave() {
+	d := &f.drain
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.active--
+	if d.closing && d.active == 0 {
+		close(d.idle)
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newShutdownFrontend returns a Frontend over a fake store holding one user
+func newShutdownFrontend(t *testing.T) (*Frontend, *fakeStore, int64) {
+	t.Helper()
+	db, store := newFakeDB(t)
+	f, err := NewFrontendWithDB(db, DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	id := store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com"})
+	return f, store, id
+}
+
+// blockSelects makes the store hold every SELECT until release is closed,
+// signalling started as each one arrives
+func blockSelects(store *fakeStore) (started chan struct{}, release chan struct{}) {
+	started, release = make(chan struct{}, 1), make(chan struct{})
+	store.fail = func(query string) error {
+		if strings.HasPrefix(query, "SELECT") {
+			started <- struct{}{}
+			<-release
+		}
+		return nil
+	}
+	return started, release
+}
+
+// awaitClosing waits until Shutdown has started refusing operations
+func awaitClosing(t *testing.T, f *Frontend) {
+	t.Helper()
+	for range 1000 {
+		f.drain.mu.Lock()
+		closing := f.drain.closing
+		f.drain.mu.Unlock()
+		if closing {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Shutdown did not start")
+}
+
+func TestShutdownWaitsForInFlightOperations(t *testing.T) {
+	f, store, id := newShutdownFrontend(t)
+	started, release := blockSelects(store)
+
+	getErr := make(chan error, 1)
+	go func() {
+		_, err := f.GetUserByID(context.Background(), id)
+		getErr <- err
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- f.Shutdown(context.Background()) }()
+	awaitClosing(t, f)
+
+	if _, err := f.GetUserByID(context.Background(), id); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("GetUserByID during shutdown = %v, want ErrShuttingDown", err)
+	}
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned %v with an operation in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-getErr; err != nil {
+		t.Errorf("in-flight GetUserByID = %v, want nil", err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown = %v, want nil", err)
+	}
+	if f.drain.active != 0 {
+		t.Errorf("drain counter = %d after shutdown, want 0", f.drain.active)
+	}
+}
+
+func TestShutdownLetsInFlightTransactionFinish(t *testing.T) {
+	f, _, id := newShutdownFrontend(t)
+
+	inTx, proceed := make(chan struct{}), make(chan struct{})
+	shutdownErr := make(chan error, 1)
+	var insideErr, outsideErr error
+	txErr := make(chan error, 1)
+	go func() {
+		txErr <- f.ExecuteInTransaction(context.Background(), func(tx *Tx) error {
+			close(inTx)
+			<-proceed
+			_, insideErr = f.GetUserByID(tx.Context(), id)
+			_, outsideErr = f.GetUserByID(context.Background(), id)
+			return nil
+		})
+	}()
+	<-inTx
+
+	go func() { shutdownErr <- f.Shutdown(context.Background()) }()
+	awaitClosing(t, f)
+	close(proceed)
+
+	if err := <-txErr; err != nil {
+		t.Fatalf("ExecuteInTransaction = %v, want nil", err)
+	}
+	if insideErr != nil {
+		t.Errorf("GetUserByID(tx.Context()) during shutdown = %v, want nil", insideErr)
+	}
+	if !errors.Is(outsideErr, ErrShuttingDown) {
+		t.Errorf("GetUserByID outside the transaction = %v, want ErrShuttingDown", outsideErr)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown = %v, want nil", err)
+	}
+	if err := f.ExecuteInTransaction(context.Background(), func(*Tx) error { return nil }); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("ExecuteInTransaction after shutdown = %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	f, store, id := newShutdownFrontend(t)
+	started, release := blockSelects(store)
+
+	getErr := make(chan error, 1)
+	go func() {
+		_, err := f.GetUserByID(context.Background(), id)
+		getErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := f.Shutdown(ctx)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want ErrTimeout wrapping context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	<-getErr
+	if f.drain.active != 0 {
+		t.Errorf("drain counter = %d after the operation finished, want 0", f.drain.active)
+	}
+}
+
+func TestShutdownWhenIdle(t *testing.T) {
+	f, _, id := newShutdownFrontend(t)
+	if err := f.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown = %v, want nil", err)
+	}
+	if _, err := f.GetUserByID(context.Background(), id); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("GetUserByID after shutdown = %v, want ErrShuttingDown", err)
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// SortField selects the column search results are ordered by
+type SortField int
+
+const (
+	// SortByCreatedAt orders by creation time, the SearchUsers default
+	SortByCreatedAt SortField = iota
+	// SortByUsername orders by username
+	SortByUsername
+	// SortByEmail orders by email
+	SortByEmail
+	// SortByID orders by user ID
+	SortByID
+)
+
+// UserSort orders search results. ORDER BY cannot be a bound parameter, so
+// only the fields above are accepted and they map to Schema columns. The
+// zero value is newest first, matching SearchUsers.
+type UserSort struct {
+	Field     SortField
+	Ascending bool
+}
+
+// SearchUsersSorted is SearchUsers with an explicit ordering. An unknown
+// Field returns ErrInvalidInput.
+func (f *Frontend) SearchUsersSorted(ctx context.Context, searchTerm string, limit int, sort UserSort) ([]*User, error) {
+	return instrumentResult(ctx, f, "SearchUsersSorted", func(ctx context.Context) ([]*User, error) {
+		return cancellable(ctx, f, f.reader(), func(q querier) ([]*User, error) {
+			return f.searchUsers(ctx, q, searchTerm, limit, sort)
+		})
+	})
+}
+
+// SearchUsersSorted searches for users with an explicit ordering within the
+// transaction
+func (t *Tx) SearchUsersSorted(ctx context.Context, searchTerm string, limit int, sort UserSort) ([]*User, error) {
+	return t.f.searchUsers(ctx, t.tx, searchTerm, limit, sort)
+}
+
+// orderBy maps sort onto an ORDER BY list built only from schema identifiers.
+// The ID breaks ties so pages are stable when the sort column repeats.
+func (f *Frontend) orderBy(sort UserSort) (string, error) {
+	s := f.schema
+	var column string
+	switch sort.Field {
+	case SortByCreatedAt:
+		column = s.CreatedAtColumn
+	case SortByUsername:
+		column = s.UsernameColumn
+	case SortByEmail:
+		column = s.EmailColumn
+	case SortByID:
+		column = s.IDColumn
+	default:
+		return "", fmt.Errorf("%w: unknown sort field", ErrInvalidInput)
+	}
+
+	direction := "DESC"
+	if sort.Ascending {
+		direction = "ASC"
+	}
+	if column == s.IDColumn {
+		return column + " " + direction, nil
+	}
+	return fmt.Sprintf("%s %s, %s %s", column, direction, s.IDColumn, direction), nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// stmtCache maps a query, as written with $N placeholders, to its prepared
+// statement on one pool. It is filled during construction and only read
+// afterwards, so it needs no locking.
+type stmtCache map[string]*sql.Stmt
+
+// hotQueries returns the statements prepared when Config.UsePreparedStatements
+// is set. The texts must match what the core methods build exactly, since the
+// cache is keyed by query text.
+func (f *Frontend) hotQueries() []string {
+	return []string{
+		f.selectUserQuery(f.schema.IDColumn),
+		f.insertUserQuery(f.insertColumns()),
+		f.updateUserQuery(),
+		f.deleteUserQuery(),
+	}
+}
+
+// prepareStatements prepares the hot queries on the primary and, when
+// configured, on the replica
+func (f *Frontend) prepareStatements() error {
+	if !f.config.UsePreparedStatements {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.connectTimeout())
+	defer cancel()
+
+	var err error
+	if f.stmts, err = f.preparePrimary(ctx, f.db); err != nil {
+		return err
+	}
+	if f.replica != nil {
+		f.replicaStmts, err = f.prepareReplica(ctx, f.replica)
+	}
+	return err
+}
+
+// preparePrimary prepares every hot query on db, or returns nil when prepared
+// statements are disabled
+func (f *Frontend) preparePrimary(ctx context.Context, db *sql.DB) (stmtCache, error) {
+	if !f.config.UsePreparedStatements {
+		return nil, nil
+	}
+	return f.prepareOn(ctx, db, f.hotQueries())
+}
+
+// prepareReplica prepares the hot read on a replica pool. Only the read is
+// useful there, since writes always go to the primary.
+func (f *Frontend) prepareReplica(ctx context.Context, db *sql.DB) (stmtCache, error) {
+	if !f.config.UsePreparedStatements {
+		return nil, nil
+	}
+	return f.prepareOn(ctx, db, []string{f.selectUserQuery(f.schema.IDColumn)})
+}
+
+// prepareOn prepares queries on db. On failure the statements prepared so far
+// are closed.
+func (f *Frontend) prepareOn(ctx context.Context, db *sql.DB, queries []string) (stmtCache, error) {
+	cache := make(stmtCache, len(queries))
+	for _, query := range queries {
+		// Every placeholder contains a '$', so this is always enough
+		// arguments for rebind to rewrite them all. The tenant is bound
+		// after the other arguments, as bindTenant does at run time.
+		bound := tenantBound(query, maxPlaceholder(query))
+		rebound, _ := f.rebind(bound, make([]any, strings.Count(bound, "$")))
+		stmt, err := db.PrepareContext(ctx, rebound)
+		if err != nil {
+			cache.close()
+			return nil, databaseError(err)
+		}
+		cache[query] = stmt
+	}
+	return cache, nil
+}
+
+// preparedStmt returns the prepared statement for query on q, or nil when
+// the query is not cached for that pool. Transactions on the primary get a
+// transaction-specific copy, which is closed when the transaction ends.
+func (f *Frontend) preparedStmt(ctx context.Context, q querier, query string) *sql.Stmt {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	switch q := q.(type) {
+	case *sql.Tx:
+		if stmt := f.stmts[query]; stmt != nil {
+			return q.StmtContext(ctx, stmt)
+		}
+	case *sql.DB:
+		if q == f.db {
+			return f.stmts[query]
+		}
+		if q == f.replica {
+			return f.replicaStmts[query]
+		}
+	}
+	return nil
+}
+
+// close closes every statement in the cache and returns the last error
+func (c stmtCache) close() error {
+	var err error
+	for _, stmt := range c {
+		if closeErr := stmt.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+// benchmarkGetUserByID reads one user repeatedly. Besides time per call it
+// reports prepares/op, the statements the server would have to parse for
+// each call: the fake driver does no parsing itself, so the count is the
+// evidence for what the cache saves against a real database.
+func benchmarkGetUserByID(b *testing.B, prepared bool) {
+	db, store := newFakeDB(b)
+	config := DefaultConfig()
+	config.UsePreparedStatements = prepared
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		b.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	id := store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com"})
+	ctx := context.Background()
+
+	// Warm the pool so the connection's statement is already prepared
+	if _, err := f.GetUserByID(ctx, id); err != nil {
+		b.Fatalf("GetUserByID: %v", err)
+	}
+	store.mu.Lock()
+	before := store.prepares
+	store.mu.Unlock()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := f.GetUserByID(ctx, id); err != nil {
+			b.Fatalf("GetUserByID: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	b.ReportMetric(float64(store.prepares-before)/float64(b.N), "prepares/op")
+}
+
+func BenchmarkGetUserByIDUncached(b *testing.B) { benchmarkGetUserByID(b, false) }
+func BenchmarkGetUserByIDCached(b *testing.B)   { benchmarkGetUserByID(b, true) }
+
+func TestPreparedStatementsAvoidReparsing(t *testing.T) {
+	for _, prepared := range []bool{false, true} {
+		db, store := newFakeDB(t)
+		config := DefaultConfig()
+		config.UsePreparedStatements = prepared
+		f, err := NewFrontendWithDB(db, config)
+		if err != nil {
+			t.Fatalf("NewFrontendWithDB: %v", err)
+		}
+		id := store.seed(map[string]driver.Value{"username": "alice", "email": "alice@example.com"})
+
+		const calls = 10
+		before := store.prepares
+		for range calls {
+			if _, err := f.GetUserByID(context.Background(), id); err != nil {
+				t.Fatalf("GetUserByID: %v", err)
+			}
+		}
+		// Cached statements were prepared during construction
+		want := calls
+		if prepared {
+			want = 0
+		}
+		if got := store.prepares - before; got != want {
+			t.Errorf("UsePreparedStatements=%v: %d prepares for %d calls, want %d", prepared, got, calls, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package db
+
+import "context"
+
+// UserStore is the set of user operations shared by Frontend and Tx. Accept
+// it instead of *Frontend in business logic so unit tests can substitute an
+// in-memory implementation such as dbtest.FakeStore.
+type UserStore interface {
+	GetUserByID(ctx context.Context, userID int64) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	CreateUser(ctx context.Context, username, email string) (*User, error)
+	SearchUsers(ctx context.Context, searchTerm string, limit int) ([]*User, error)
+	CountUsers(ctx context.Context) (int64, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]*User, error)
+	UpdateUser(ctx context.Context, userID int64, username, email string) error
+	UpdateUserEmail(ctx context.Context, userID int64, email string) error
+	UpdateUserUsername(ctx context.Context, userID int64, username string) error
+	DeleteUser(ctx context.Context, userID int64) error
+}
+
+var (
+	_ UserStore = (*Frontend)(nil)
+	_ UserStore = (*Tx)(nil)
+)
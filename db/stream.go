@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// SearchUsersStream runs the same search as SearchUsers without a row limit
+// and calls fn for each match in turn, so exports use constant memory. If fn
+// returns an error, iteration stops and that error is returned unchanged.
+// Cancelling ctx stops iteration promptly with ctx.Err(). fn runs while the
+// query holds a connection, so it should not block for long; give large
+// exports a context deadline longer than Config.QueryTimeout.
+func (f *Frontend) SearchUsersStream(ctx context.Context, searchTerm string, fn func(*User) error) error {
+	return f.instrument(ctx, "SearchUsersStream", func(ctx context.Context) error {
+		_, err := cancellable(ctx, f, f.reader(), func(q querier) (struct{}, error) {
+			return struct{}{}, f.searchUsersStream(ctx, q, searchTerm, fn)
+		})
+		return err
+	})
+}
+
+// SearchUsersStream streams search results within the transaction
+func (t *Tx) SearchUsersStream(ctx context.Context, searchTerm string, fn func(*User) error) error {
+	return t.f.searchUsersStream(ctx, t.tx, searchTerm, fn)
+}
+
+// searchUsersStream iterates the search results and hands each row to fn
+func (f *Frontend) searchUsersStream(ctx context.Context, q querier, searchTerm string, fn func(*User) error) error {
+	if fn == nil {
+		return fmt.Errorf("%w: callback is required", ErrInvalidInput)
+	}
+	match, args, err := f.searchFilter(searchTerm)
+	if err != nil {
+		return err
+	}
+
+	s := f.schema
+	query := fmt.Sprintf(`SELECT %s FROM %s%s ORDER BY %s DESC`,
+		f.userColumns(), s.Table, f.where(match), s.CreatedAtColumn)
+
+	rows, err := f.query(ctx, q, query, args...)
+	if err != nil {
+		return databaseError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		user, err := f.scanUser(rows)
+		if err != nil {
+			return databaseError(err)
+		}
+		countRows(ctx, 1)
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return databaseError(err)
+	}
+	return nil
+}
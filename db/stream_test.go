@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newStreamFrontend returns a Frontend over a fake store holding n users,
+// all of whom match any search term
+func newStreamFrontend(t *testing.T, n int) *Frontend {
+	t.Helper()
+	db, store := newFakeDB(t)
+	f, err := NewFrontendWithDB(db, DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+	for i := range n {
+		store.seed(map[string]driver.Value{
+			"username": fmt.Sprintf("user%d", i),
+			"email":    fmt.Sprintf("user%d@example.com", i),
+		})
+	}
+	return f
+}
+
+func TestSearchUsersStreamVisitsEveryMatch(t *testing.T) {
+	f := newStreamFrontend(t, 3)
+	calls := 0
+	err := f.SearchUsersStream(context.Background(), "user", func(*User) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("SearchUsersStream = %v after %d calls, want nil after 3", err, calls)
+	}
+}
+
+func TestSearchUsersStreamCallbackErrorStops(t *testing.T) {
+	f := newStreamFrontend(t, 3)
+	stop := errors.New("export full")
+	calls := 0
+	err := f.SearchUsersStream(context.Background(), "user", func(*User) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("SearchUsersStream = %v, want the callback's error unchanged", err)
+	}
+	if calls != 2 {
+		t.Errorf("callback ran %d times, want iteration to stop after 2", calls)
+	}
+}
+
+func TestSearchUsersStreamStopsOnCancel(t *testing.T) {
+	f := newStreamFrontend(t, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := f.SearchUsersStream(ctx, "user", func(*User) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SearchUsersStream = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback ran %d times, want iteration to stop after 1", calls)
+	}
+}
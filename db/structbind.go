@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InsertStruct inserts one row into table with a column for each field of v
+// tagged `db:"column"`. v must be a struct or a pointer to one. Fields tagged
+// `db:"-"` or without a tag are skipped, and `db:"column,omitempty"` skips the
+// field when it holds its zero value, which suits generated IDs. Embedded
+// structs without a tag contribute their own tagged fields.
+//
+// The table and every column name are checked against the same identifier
+// allowlist as Config.Schema before they reach SQL; field values are always
+// bound as parameters.
+func (f *Frontend) InsertStruct(ctx context.Context, table string, v any) error {
+	return f.instrument(ctx, "InsertStruct", func(ctx context.Context) error {
+		return f.insertStruct(ctx, f.primary(), table, v)
+	})
+}
+
+// InsertStruct inserts one tagged struct within the transaction
+func (t *Tx) InsertStruct(ctx context.Context, table string, v any) error {
+	return t.f.insertStruct(ctx, t.tx, table, v)
+}
+
+// insertStruct builds and runs the INSERT for a tagged struct
+func (f *Frontend) insertStruct(ctx context.Context, q querier, table string, v any) error {
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("%w: invalid table name", ErrInvalidInput)
+	}
+	columns, values, err := structColumns(v)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: struct has no db-tagged fields to insert", ErrInvalidInput)
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`,
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	if _, err := f.execCounted(ctx, q, query, values...); err != nil {
+		if isUniqueViolation(err) {
+			return duplicateError()
+		}
+		return databaseError(err)
+	}
+	return nil
+}
+
+// structColumns returns the allowlisted column names and values of the
+// db-tagged fields of v, in field order
+func structColumns(v any) ([]string, []any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("%w: nil struct", ErrInvalidInput)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%w: InsertStruct requires a struct", ErrInvalidInput)
+	}
+
+	var columns []string
+	var values []any
+	seen := make(map[string]bool)
+	var walk func(rv reflect.Value) error
+	walk = func(rv reflect.Value) error {
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			tag, hasTag := field.Tag.Lookup("db")
+			if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+				if err := walk(rv.Field(i)); err != nil {
+					return err
+				}
+				continue
+			}
+			if !field.IsExported() || !hasTag || tag == "-" {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(tag, ",")
+			if !identifierPattern.MatchString(name) {
+				return fmt.Errorf("%w: invalid column name in db tag of field %s", ErrInvalidInput, field.Name)
+			}
+			if seen[name] {
+				return fmt.Errorf("%w: duplicate column %s in db tags", ErrInvalidInput, name)
+			}
+			value := rv.Field(i)
+			if opts == "omitempty" && value.IsZero() {
+				continue
+			}
+			seen[name] = true
+			columns = append(columns, name)
+			values = append(values, value.Interface())
+		}
+		return nil
+	}
+	if err := walk(rv); err != nil {
+		return nil, nil, err
+	}
+	return columns, values, nil
+}
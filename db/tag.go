@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// opKey is the context key under which instrument records the operation name
+type opKey struct{}
+
+// withOp returns a context recording op as the public method being run
+func withOp(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey{}, op)
+}
+
+// opFromContext returns the operation recorded by instrument, or ""
+func opFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(opKey{}).(string)
+	return op
+}
+
+// tagQuery appends a sqlcommenter-style comment naming the operation and actor
+// to query when Config.TagQueries is set, for example
+//
+//	SELECT ... /*actor='42',op='GetUserByID'*/
+//
+// Values are URL-encoded as the sqlcommenter format specifies, which also
+// encodes '*', '/' and quotes, so no value can close the comment or reach
+// the SQL around it.
+func (f *Frontend) tagQuery(ctx context.Context, query string) string {
+	if !f.config.TagQueries {
+		return query
+	}
+
+	var tags []string
+	if actor := f.actor(ctx); actor != "" {
+		tags = append(tags, "actor='"+url.QueryEscape(actor)+"'")
+	}
+	if op := opFromContext(ctx); op != "" {
+		tags = append(tags, "op='"+url.QueryEscape(op)+"'")
+	}
+	if len(tags) == 0 {
+		return query
+	}
+	return query + " /*" + strings.Join(tags, ",") + "*/"
+}
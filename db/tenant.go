@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// tenantKey is the context key used by WithTenant
+type tenantKey struct{}
+
+// WithTenant returns a context scoping every operation made with it to
+// tenant, a value of Schema.TenantColumn such as an organization ID. It is
+// bound as a query parameter.
+func WithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, if any
+func TenantFromContext(ctx context.Context) (any, bool) {
+	tenant := ctx.Value(tenantKey{})
+	return tenant, tenant != nil
+}
+
+// tenantPlaceholder marks where the tenant is bound in queries built by this
+// package. bindTenant turns it into the next free $N placeholder, so query
+// builders need not know how many arguments precede it.
+const tenantPlaceholder = "$tenant"
+
+// tenantCondition returns the condition restricting rows to the current
+// tenant, or "" when Schema.TenantColumn is unset
+func (f *Frontend) tenantCondition() string {
+	if f.schema.TenantColumn == "" {
+		return ""
+	}
+	return f.schema.TenantColumn + " = " + tenantPlaceholder
+}
+
+// bindTenant replaces the tenant placeholder in query with the placeholder
+// after the last argument and binds the tenant from ctx to it. Without a
+// tenant in ctx the value is NULL, which matches no row, so a missing tenant
+// fails closed.
+func (f *Frontend) bindTenant(ctx context.Context, query string, args []any) (string, []any) {
+	if !strings.Contains(query, tenantPlaceholder) {
+		return query, args
+	}
+	tenant, _ := TenantFromContext(ctx)
+	query = tenantBound(query, len(args))
+	return query, append(args[:len(args):len(args)], tenant)
+}
+
+// tenantBound replaces the tenant placeholder in a query with n arguments
+func tenantBound(query string, n int) string {
+	return strings.ReplaceAll(query, tenantPlaceholder, fmt.Sprintf("$%d", n+1))
+}
+
+// maxPlaceholder returns the highest $N placeholder number in query, which
+// for the queries built here is their argument count
+func maxPlaceholder(query string) int {
+	highest := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] != '$' {
+			continue
+		}
+		n := 0
+		for j := i + 1; j < len(query) && query[j] >= '0' && query[j] <= '9'; j++ {
+			n = n*10 + int(query[j]-'0')
+		}
+		highest = max(highest, n)
+	}
+	return highest
+}
+
+// requireTenant rejects a write when tenancy is enabled and ctx carries no
+// tenant, so no row is stored without one
+func (f *Frontend) requireTenant(ctx context.Context) error {
+	if f.schema.TenantColumn == "" {
+		return nil
+	}
+	if _, ok := TenantFromContext(ctx); !ok {
+		return fmt.Errorf("%w: tenant is required", ErrInvalidInput)
+	}
+	return nil
+}
+
+// tenantInsertColumns appends the tenant column and its placeholder to an
+// INSERT's columns and values when Schema.TenantColumn is set
+func (f *Frontend) tenantInsertColumns(columns, placeholders []string) ([]string, []string) {
+	if f.schema.TenantColumn == "" {
+		return columns, placeholders
+	}
+	return append(columns, f.schema.TenantColumn), append(placeholders, tenantPlaceholder)
+}
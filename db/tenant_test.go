@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// newTenantFrontend returns a Frontend scoped by tenant_id over a fake store
+// seeded with one user in tenant "acme" and one in tenant "globex"
+func newTenantFrontend(t *testing.T) (f *Frontend, store *fakeStore, acmeID, globexID int64) {
+	t.Helper()
+	db, store := newFakeDB(t)
+	config := DefaultConfig()
+	config.Schema.TenantColumn = "tenant_id"
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	acmeID = store.seed(map[string]driver.Value{"username": "alice", "email": "alice@acme.io", "tenant_id": "acme"})
+	globexID = store.seed(map[string]driver.Value{"username": "bob", "email": "bob@globex.io", "tenant_id": "globex"})
+	return f, store, acmeID, globexID
+}
+
+func TestTenantCannotReachOtherTenantsRows(t *testing.T) {
+	f, store, acmeID, globexID := newTenantFrontend(t)
+	ctx := WithTenant(context.Background(), "acme")
+
+	if _, err := f.GetUserByID(ctx, acmeID); err != nil {
+		t.Fatalf("GetUserByID(own user) = %v, want nil", err)
+	}
+	if _, err := f.GetUserByID(ctx, globexID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserByID(other tenant) = %v, want ErrNotFound", err)
+	}
+	if err := f.UpdateUser(ctx, globexID, "mallory", "mallory@acme.io"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateUser(other tenant) = %v, want ErrNotFound", err)
+	}
+	if err := f.DeleteUser(ctx, globexID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteUser(other tenant) = %v, want ErrNotFound", err)
+	}
+
+	row := store.find(globexID)
+	if row == nil {
+		t.Fatal("other tenant's user was deleted")
+	}
+	if row["username"] != "bob" || row["email"] != "bob@globex.io" {
+		t.Errorf("other tenant's user was modified: %v", row)
+	}
+}
+
+func TestTenantStampedOnInsert(t *testing.T) {
+	f, store, _, _ := newTenantFrontend(t)
+	ctx := WithTenant(context.Background(), "acme")
+
+	if _, err := f.CreateUser(ctx, "carol", "carol@acme.io"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := f.CreateUsers(ctx, []NewUser{
+		{Username: "dave", Email: "dave@acme.io"},
+		{Username: "erin", Email: "erin@acme.io"},
+	}); err != nil {
+		t.Fatalf("CreateUsers: %v", err)
+	}
+	if n, err := f.BulkCopyUsers(ctx, []NewUser{{Username: "frank", Email: "frank@acme.io"}}); err != nil || n != 1 {
+		t.Fatalf("BulkCopyUsers = %d, %v, want 1, nil", n, err)
+	}
+
+	stamped := map[string]bool{}
+	for _, row := range store.all() {
+		username, _ := row["username"].(string)
+		if row["tenant_id"] == "acme" {
+			stamped[username] = true
+		}
+	}
+	for _, username := range []string{"carol", "dave", "erin", "frank"} {
+		if !stamped[username] {
+			t.Errorf("%s was not stored with tenant acme", username)
+		}
+	}
+}
+
+func TestTenantReadsSeeOnlyCurrentTenant(t *testing.T) {
+	f, store, _, _ := newTenantFrontend(t)
+	store.seed(map[string]driver.Value{"username": "amy", "email": "amy@acme.io", "tenant_id": "acme"})
+	ctx := WithTenant(context.Background(), "globex")
+
+	onlyBob := func(name string, users []*User, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(users) != 1 || users[0].Username != "bob" {
+			t.Errorf("%s returned %d users, want only bob", name, len(users))
+		}
+	}
+
+	users, err := f.SearchUsers(ctx, "a", 10)
+	onlyBob("SearchUsers", users, err)
+	users, err = f.ListUsers(ctx, 10, 0)
+	onlyBob("ListUsers", users, err)
+
+	count, err := f.CountUsers(ctx)
+	if err != nil {
+		t.Fatalf("CountUsers: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("CountUsers = %d, want 1", count)
+	}
+}
+
+func TestTenantMissingFailsClosed(t *testing.T) {
+	f, store, acmeID, _ := newTenantFrontend(t)
+	ctx := context.Background()
+	before := len(store.all())
+
+	if _, err := f.CreateUser(ctx, "carol", "carol@acme.io"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CreateUser without tenant = %v, want ErrInvalidInput", err)
+	}
+	if _, err := f.CreateUsers(ctx, []NewUser{{Username: "dave", Email: "dave@acme.io"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CreateUsers without tenant = %v, want ErrInvalidInput", err)
+	}
+	if _, err := f.BulkCopyUsers(ctx, []NewUser{{Username: "erin", Email: "erin@acme.io"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("BulkCopyUsers without tenant = %v, want ErrInvalidInput", err)
+	}
+	if after := len(store.all()); after != before {
+		t.Errorf("%d rows stored without a tenant", after-before)
+	}
+
+	// Reads bind a NULL tenant, which matches no row
+	if _, err := f.GetUserByID(ctx, acmeID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserByID without tenant = %v, want ErrNotFound", err)
+	}
+	if count, err := f.CountUsers(ctx); err != nil || count != 0 {
+		t.Errorf("CountUsers without tenant = %d, %v, want 0, nil", count, err)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import "context"
+
+// Tracer starts a span around each database operation. It covers the small
+// subset of OpenTelemetry used here so the package does not pull in the otel
+// dependency; an adapter over go.opentelemetry.io/otel/trace.Tracer is a few
+// lines (see README). Implementations must be safe for concurrent use.
+type Tracer interface {
+	Start(ctx context.Context, op string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an in-progress trace span
+type Span interface {
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a span attribute. Attributes only describe the operation;
+// query arguments are never attached.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// startSpan starts a span for op when a Tracer is configured. The returned
+// span is never nil.
+func (f *Frontend) startSpan(ctx context.Context, op string) (context.Context, Span) {
+	if f.config.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	attrs := []Attribute{
+		{Key: "db.system", Value: string(f.config.driver())},
+		{Key: "db.operation", Value: op},
+		{Key: "db.sql.table", Value: f.schema.Table},
+	}
+	if actor := f.actor(ctx); actor != "" {
+		attrs = append(attrs, Attribute{Key: "enduser.id", Value: actor})
+	}
+	return f.config.Tracer.Start(ctx, op, attrs...)
+}
+
+// noopSpan is used when tracing is disabled
+type noopSpan struct{}
+
+func (noopSpan) RecordError(error) {}
+func (noopSpan) End()              {}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tx wraps a database transaction and exposes the same validated,
+// parameterized operations as Frontend. A Tx is only valid inside the
+// callback passed to ExecuteInTransaction.
+type Tx struct {
+	tx *sql.Tx
+	f  *Frontend
+
+	// ctx carries this Tx for nesting; see Context
+	ctx context.Context
+	// parent is the enclosing transaction when this Tx is a savepoint
+	parent *Tx
+
+	// audit holds events for writes made in this transaction, delivered to
+	// Config.AuditSink after commit. savepoints numbers savepoint names and
+	// is only used on the outermost Tx. Both are guarded by mu.
+	mu         sync.Mutex
+	audit      []AuditEvent
+	savepoints int
+}
+
+// GetUserByID retrieves a user by ID within the transaction
+func (t *Tx) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	return t.f.getUserByID(ctx, t.tx, userID)
+}
+
+// GetUserByIDForUpdate retrieves a user by ID and locks the row until the
+// transaction ends, so a read-check-update sequence cannot lose a concurrent
+// update. It must be called on a transaction; a nil Tx returns
+// ErrInvalidInput. SQLite locks the whole database on write instead and takes
+// no row lock.
+//
+// Every other writer of the user, and every other locking read of it, waits
+// for the lock until the transaction commits or rolls back, so keep such
+// transactions short and avoid slow work while holding the lock. Locking
+// several users in different orders in concurrent transactions can deadlock;
+// PostgreSQL (40P01) and MySQL (error 1213) then abort one of them. That
+// error, like a lock wait timeout (55P03, MySQL error 1205), is retried by
+// ExecuteInTransaction when Config.TxMaxRetries is set.
+func (t *Tx) GetUserByIDForUpdate(ctx context.Context, userID int64) (*User, error) {
+	if t == nil {
+		return nil, fmt.Errorf("%w: GetUserByIDForUpdate requires a transaction", ErrInvalidInput)
+	}
+	return t.f.getUserByIDForUpdate(ctx, t.tx, userID)
+}
+
+// GetUserByUsername retrieves a user by username within the transaction
+func (t *Tx) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return t.f.getUserByUsername(ctx, t.tx, username)
+}
+
+// GetUserByEmail retrieves a user by email within the transaction
+func (t *Tx) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return t.f.getUserByEmail(ctx, t.tx, email)
+}
+
+// UserExists reports whether a user exists within the transaction
+func (t *Tx) UserExists(ctx context.Context, userID int64) (bool, error) {
+	return t.f.userExists(ctx, t.tx, userID)
+}
+
+// CreateUser creates a new user with validated input within the transaction
+func (t *Tx) CreateUser(ctx context.Context, username, email string) (*User, error) {
+	return txAuditWrite(ctx, t, "CreateUser", func(q querier) (*User, []int64, error) {
+		return createdUser(t.f.createUser(ctx, q, username, email))
+	})
+}
+
+// SearchUsers searches for users within the transaction
+func (t *Tx) SearchUsers(ctx context.Context, searchTerm string, limit int) ([]*User, error) {
+	return t.f.searchOrListUsers(ctx, t.tx, searchTerm, limit)
+}
+
+// CountUsers returns the total number of users within the transaction
+func (t *Tx) CountUsers(ctx context.Context) (int64, error) {
+	return t.f.countUsers(ctx, t.tx)
+}
+
+// CountUsersMatching counts users matching searchTerm within the transaction
+func (t *Tx) CountUsersMatching(ctx context.Context, searchTerm string) (int64, error) {
+	return t.f.countUsersMatching(ctx, t.tx, searchTerm)
+}
+
+// ListUsers returns a page of users within the transaction
+func (t *Tx) ListUsers(ctx context.Context, limit, offset int) ([]*User, error) {
+	return t.f.listUsers(ctx, t.tx, limit, offset)
+}
+
+// ListUsersAfter returns the next page of users after afterID within the transaction
+func (t *Tx) ListUsersAfter(ctx context.Context, afterID int64, limit int) ([]*User, error) {
+	return t.f.listUsersAfter(ctx, t.tx, afterID, limit)
+}
+
+// ListUsersCreatedBetween returns a page of users created in [from, to)
+// within the transaction
+func (t *Tx) ListUsersCreatedBetween(ctx context.Context, from, to time.Time, limit, offset int) ([]*User, error) {
+	return t.f.listUsersCreatedBetween(ctx, t.tx, from, to, limit, offset)
+}
+
+// UpdateUser updates user information with validated input within the transaction
+func (t *Tx) UpdateUser(ctx context.Context, userID int64, username, email string) error {
+	return t.auditExec(ctx, "UpdateUser", userID, func(q querier) error {
+		return t.f.updateUser(ctx, q, userID, username, email)
+	})
+}
+
+// UpdateUserAtVersion updates a user only if the stored version matches, within the transaction
+func (t *Tx) UpdateUserAtVersion(ctx context.Context, userID int64, username, email string, version int64) error {
+	return t.auditExec(ctx, "UpdateUserAtVersion", userID, func(q querier) error {
+		return t.f.updateUserAtVersion(ctx, q, userID, username, email, version)
+	})
+}
+
+// UpdateUserEmail changes only the email of a user within the transaction
+func (t *Tx) UpdateUserEmail(ctx context.Context, userID int64, email string) error {
+	return t.auditExec(ctx, "UpdateUserEmail", userID, func(q querier) error {
+		return t.f.updateUserEmail(ctx, q, userID, email)
+	})
+}
+
+// UpdateUserUsername changes only the username of a user within the transaction
+func (t *Tx) UpdateUserUsername(ctx context.Context, userID int64, username string) error {
+	return t.auditExec(ctx, "UpdateUserUsername", userID, func(q querier) error {
+		return t.f.updateUserUsername(ctx, q, userID, username)
+	})
+}
+
+// DeleteUser deletes a user by ID within the transaction
+func (t *Tx) DeleteUser(ctx context.Context, userID int64) error {
+	return t.auditExec(ctx, "DeleteUser", userID, func(q querier) error {
+		return t.f.deleteUser(ctx, q, userID)
+	})
+}
+
+// RestoreUser undoes a soft delete within the transaction
+func (t *Tx) RestoreUser(ctx context.Context, userID int64) error {
+	return t.auditExec(ctx, "RestoreUser", userID, func(q querier) error {
+		return t.f.restoreUser(ctx, q, userID)
+	})
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UpsertKey selects the unique column UpsertUser resolves conflicts on
+type UpsertKey int
+
+const (
+	// UpsertOnEmail matches existing users by email and updates the username
+	UpsertOnEmail UpsertKey = iota
+	// UpsertOnUsername matches existing users by username and updates the email
+	UpsertOnUsername
+)
+
+// UpsertUser inserts a user, or updates the existing user that has the same
+// key column, in a single statement. The column named by key must have a
+// unique index. created reports whether a new row was inserted. With
+// Config.SoftDelete, a conflict with a soft-deleted user returns ErrDuplicate
+// instead of modifying it. PostgreSQL and MySQL are supported; SQLite returns
+// ErrUnsupported.
+func (f *Frontend) UpsertUser(ctx context.Context, key UpsertKey, username, email string) (user *User, created bool, err error) {
+	err = f.instrument(ctx, "UpsertUser", func(ctx context.Context) error {
+		user, err = auditWrite(ctx, f, "UpsertUser", func(q querier) (*User, []int64, error) {
+			u, inserted, err := f.upsertUser(ctx, q, key, username, email)
+			created = inserted
+			return createdUser(u, err)
+		})
+		return err
+	})
+	return user, created, err
+}
+
+// UpsertUser inserts or updates a user within the transaction
+func (t *Tx) UpsertUser(ctx context.Context, key UpsertKey, username, email string) (*User, bool, error) {
+	var created bool
+	user, err := txAuditWrite(ctx, t, "UpsertUser", func(q querier) (*User, []int64, error) {
+		u, inserted, err := t.f.upsertUser(ctx, q, key, username, email)
+		created = inserted
+		return createdUser(u, err)
+	})
+	return user, created, err
+}
+
+// upsertUser validates the input and runs the upsert statement for the
+// configured driver
+func (f *Frontend) upsertUser(ctx context.Context, q querier, key UpsertKey, username, email string) (*User, bool, error) {
+	driver := f.config.driver()
+	if driver != DriverPostgres && driver != DriverMySQL {
+		return nil, false, fmt.Errorf("%w: upsert requires postgres or mysql", ErrUnsupported)
+	}
+	if err := f.validateNewUsername(username); err != nil {
+		return nil, false, err
+	}
+	if err := f.validateNewEmail(email); err != nil {
+		return nil, false, err
+	}
+	if err := f.requireTenant(ctx); err != nil {
+		return nil, false, err
+	}
+	username = f.normalizeUsername(username)
+	email = f.normalizeEmail(email)
+
+	query, target, err := f.upsertQuery(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Bound in the order of insertColumns
+	args := []any{username, email, time.Now()}
+	if f.config.OptimisticLocking {
+		args = append(args, int64(1))
+	}
+
+	if driver == DriverMySQL {
+		keyValue := email
+		if key == UpsertOnUsername {
+			keyValue = username
+		}
+		return f.upsertUserMySQL(ctx, q, query, target, keyValue, username, email, args)
+	}
+	return f.upsertUserPostgres(ctx, q, query, args)
+}
+
+// upsertQuery builds the upsert statement for key on the configured driver
+// and returns it with the conflict target column. Values are bound in the
+// order of insertColumns.
+func (f *Frontend) upsertQuery(key UpsertKey) (query, target string, err error) {
+	s := f.schema
+	var update string
+	switch key {
+	case UpsertOnEmail:
+		target, update = s.EmailColumn, s.UsernameColumn
+	case UpsertOnUsername:
+		target, update = s.UsernameColumn, s.EmailColumn
+	default:
+		return "", "", fmt.Errorf("%w: unknown upsert key", ErrInvalidInput)
+	}
+
+	columns := f.insertColumns()
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	columns, placeholders = f.tenantInsertColumns(columns, placeholders)
+
+	if f.config.driver() == DriverMySQL {
+		return f.upsertQueryMySQL(columns, placeholders, update), target, nil
+	}
+	return f.upsertQueryPostgres(columns, placeholders, target, update), target, nil
+}
+
+// upsertQueryPostgres builds INSERT ... ON CONFLICT DO UPDATE. The WHERE
+// clause leaves soft-deleted rows untouched, and RETURNING reports from the
+// xmax system column whether the row was inserted.
+func (f *Frontend) upsertQueryPostgres(columns, placeholders []string, target, update string) string {
+	s := f.schema
+	if s.TenantColumn != "" {
+		// Uniqueness is per tenant, so the index covers both columns
+		target = s.TenantColumn + ", " + target
+	}
+
+	var reset string
+	if update == s.EmailColumn {
+		reset = f.verificationReset("EXCLUDED." + update)
+	}
+
+	// Identifiers come from the validated schema; values are bound
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s%s = EXCLUDED.%s%s%s RETURNING %s, (xmax = 0)`,
+		s.Table, strings.Join(columns, ", "), strings.Join(placeholders, ", "),
+		target, reset, update, update, f.versionBump()+f.touchUpdatedAt(), f.where(), f.userColumns())
+}
+
+// upsertQueryMySQL builds INSERT ... ON DUPLICATE KEY UPDATE. MySQL has no
+// conflict target or WHERE for the update, so each assignment is guarded
+// with IF to leave a soft-deleted row, or another tenant's row under a
+// global index, as it was.
+func (f *Frontend) upsertQueryMySQL(columns, placeholders []string, update string) string {
+	s := f.schema
+	var guards []string
+	if f.config.SoftDelete {
+		guards = append(guards, s.DeletedAtColumn+" IS NULL")
+	}
+	if s.TenantColumn != "" {
+		guards = append(guards, fmt.Sprintf("%[1]s = VALUES(%[1]s)", s.TenantColumn))
+	}
+	assign := func(column, value string) string {
+		if len(guards) == 0 {
+			return column + " = " + value
+		}
+		return fmt.Sprintf("%s = IF(%s, %s, %s)", column, strings.Join(guards, " AND "), value, column)
+	}
+
+	var sets []string
+	if update == s.EmailColumn && f.config.EmailVerification {
+		// Like verificationReset, these must come before the email
+		// assignment to compare against the old value
+		changed := fmt.Sprintf("%s = VALUES(%s)", s.EmailColumn, s.EmailColumn)
+		sets = append(sets,
+			assign(s.EmailVerifiedColumn, fmt.Sprintf("(%s AND %s)", s.EmailVerifiedColumn, changed)),
+			assign(s.VerificationTokenColumn, fmt.Sprintf("IF(%s, %s, NULL)", changed, s.VerificationTokenColumn)),
+			assign(s.VerificationSentAtColumn, fmt.Sprintf("IF(%s, %s, NULL)", changed, s.VerificationSentAtColumn)))
+	}
+	sets = append(sets, assign(update, "VALUES("+update+")"))
+	if f.config.OptimisticLocking {
+		sets = append(sets, assign(s.VersionColumn, s.VersionColumn+" + 1"))
+	}
+	if f.config.TrackUpdatedAt {
+		sets = append(sets, assign(s.UpdatedAtColumn, "CURRENT_TIMESTAMP"))
+	}
+
+	// Identifiers come from the validated schema; values are bound
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s`,
+		s.Table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(sets, ", "))
+}
+
+// upsertUserPostgres runs query and scans the row it returns
+func (f *Frontend) upsertUserPostgres(ctx context.Context, q querier, query string, args []any) (*User, bool, error) {
+	var inserted bool
+	user, err := f.scanUser(f.queryRow(ctx, q, query, args...), &inserted)
+	if err != nil {
+		// No row comes back when the conflicting user is soft-deleted
+		if errors.Is(err, sql.ErrNoRows) || isUniqueViolation(err) {
+			return nil, false, duplicateError()
+		}
+		return nil, false, databaseError(err)
+	}
+
+	return user, inserted, nil
+}
+
+// upsertUserMySQL runs query and reads the row back by its key column, as
+// MySQL has no RETURNING. The affected-row count tells the outcome apart: 1
+// for an insert, 2 for an update and 0 when the row already matched.
+//
+// ON DUPLICATE KEY fires on any unique index, not just the key column, so
+// the row read back must carry both values; otherwise the conflict was with
+// another user, or with a soft-deleted one, and ErrDuplicate is returned.
+func (f *Frontend) upsertUserMySQL(ctx context.Context, q querier, query, keyColumn, keyValue, username, email string, args []any) (*User, bool, error) {
+	result, err := f.execCounted(ctx, q, query, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, false, duplicateError()
+		}
+		return nil, false, databaseError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, databaseError(err)
+	}
+
+	user, err := f.getUserWhere(ctx, q, keyColumn, keyValue)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, false, duplicateError()
+		}
+		return nil, false, err
+	}
+	// Compare case-insensitively, since MySQL's default collations do
+	if !strings.EqualFold(user.Username, username) || !strings.EqualFold(user.Email, email) {
+		return nil, false, duplicateError()
+	}
+	return user, affected == 1, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver Driver
+		key    UpsertKey
+		config func(*Config)
+		want   string
+	}{
+		{
+			name:   "postgres",
+			driver: DriverPostgres,
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username ` +
+				`RETURNING id, username, email, created_at, (xmax = 0)`,
+		},
+		{
+			name:   "postgres on username",
+			driver: DriverPostgres,
+			key:    UpsertOnUsername,
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON CONFLICT (username) DO UPDATE SET email = EXCLUDED.email ` +
+				`RETURNING id, username, email, created_at, (xmax = 0)`,
+		},
+		{
+			name:   "postgres optimistic locking",
+			driver: DriverPostgres,
+			config: func(c *Config) { c.OptimisticLocking = true },
+			want: `INSERT INTO users (username, email, created_at, version) VALUES ($1, $2, $3, $4) ` +
+				`ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username, version = version + 1 ` +
+				`RETURNING id, username, email, created_at, version, (xmax = 0)`,
+		},
+		{
+			name:   "postgres updated at",
+			driver: DriverPostgres,
+			config: func(c *Config) { c.TrackUpdatedAt = true },
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username, updated_at = CURRENT_TIMESTAMP ` +
+				`RETURNING id, username, email, created_at, updated_at, (xmax = 0)`,
+		},
+		{
+			name:   "postgres soft delete",
+			driver: DriverPostgres,
+			config: func(c *Config) { c.SoftDelete = true },
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON CONFLICT (email) DO UPDATE SET username = EXCLUDED.username WHERE deleted_at IS NULL ` +
+				`RETURNING id, username, email, created_at, (xmax = 0)`,
+		},
+		{
+			name:   "mysql",
+			driver: DriverMySQL,
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON DUPLICATE KEY UPDATE username = VALUES(username)`,
+		},
+		{
+			name:   "mysql optimistic locking",
+			driver: DriverMySQL,
+			config: func(c *Config) { c.OptimisticLocking = true },
+			want: `INSERT INTO users (username, email, created_at, version) VALUES ($1, $2, $3, $4) ` +
+				`ON DUPLICATE KEY UPDATE username = VALUES(username), version = version + 1`,
+		},
+		{
+			name:   "mysql updated at",
+			driver: DriverMySQL,
+			config: func(c *Config) { c.TrackUpdatedAt = true },
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON DUPLICATE KEY UPDATE username = VALUES(username), updated_at = CURRENT_TIMESTAMP`,
+		},
+		{
+			name:   "mysql guarded",
+			driver: DriverMySQL,
+			config: func(c *Config) {
+				c.SoftDelete = true
+				c.OptimisticLocking = true
+				c.TrackUpdatedAt = true
+			},
+			want: `INSERT INTO users (username, email, created_at, version) VALUES ($1, $2, $3, $4) ` +
+				`ON DUPLICATE KEY UPDATE username = IF(deleted_at IS NULL, VALUES(username), username), ` +
+				`version = IF(deleted_at IS NULL, version + 1, version), ` +
+				`updated_at = IF(deleted_at IS NULL, CURRENT_TIMESTAMP, updated_at)`,
+		},
+		{
+			name:   "mysql email verification",
+			driver: DriverMySQL,
+			key:    UpsertOnUsername,
+			config: func(c *Config) { c.EmailVerification = true },
+			want: `INSERT INTO users (username, email, created_at) VALUES ($1, $2, $3) ` +
+				`ON DUPLICATE KEY UPDATE email_verified = (email_verified AND email = VALUES(email)), ` +
+				`verification_token = IF(email = VALUES(email), verification_token, NULL), ` +
+				`verification_sent_at = IF(email = VALUES(email), verification_sent_at, NULL), ` +
+				`email = VALUES(email)`,
+		},
+		{
+			name:   "mysql tenant guard",
+			driver: DriverMySQL,
+			key:    UpsertOnUsername,
+			config: func(c *Config) { c.Schema.TenantColumn = "tenant_id" },
+			want: `INSERT INTO users (username, email, created_at, tenant_id) VALUES ($1, $2, $3, $tenant) ` +
+				`ON DUPLICATE KEY UPDATE email = IF(tenant_id = VALUES(tenant_id), VALUES(email), email)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := newFakeDB(t)
+			config := DefaultConfig()
+			config.Driver = tt.driver
+			if tt.config != nil {
+				tt.config(config)
+			}
+			f, err := NewFrontendWithDB(db, config)
+			if err != nil {
+				t.Fatalf("NewFrontendWithDB: %v", err)
+			}
+
+			got, _, err := f.upsertQuery(tt.key)
+			if err != nil {
+				t.Fatalf("upsertQuery: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("upsertQuery =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertUnsupportedOnSQLite(t *testing.T) {
+	db, _ := newFakeDB(t)
+	config := DefaultConfig()
+	config.Driver = DriverSQLite
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	_, _, err = f.UpsertUser(context.Background(), UpsertOnEmail, "alice", "alice@example.com")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("UpsertUser on sqlite = %v, want ErrUnsupported", err)
+	}
+}
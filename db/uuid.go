@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetUserByUUID retrieves a user by the UUID in Schema.UUIDColumn. id must
+// be in the canonical 8-4-4-4-12 hex form; it is matched in lowercase.
+func (f *Frontend) GetUserByUUID(ctx context.Context, id string) (*User, error) {
+	return instrumentResult(ctx, f, "GetUserByUUID", func(ctx context.Context) (*User, error) {
+		return f.getUserByUUID(ctx, f.reader(), id)
+	})
+}
+
+// GetUserByUUID retrieves a user by UUID within the transaction
+func (t *Tx) GetUserByUUID(ctx context.Context, id string) (*User, error) {
+	return t.f.getUserByUUID(ctx, t.tx, id)
+}
+
+// CreateUserWithUUID creates a user with a client-generated UUID. CreateUser
+// leaves Schema.UUIDColumn to its database default instead, such as
+// gen_random_uuid() on PostgreSQL.
+func (f *Frontend) CreateUserWithUUID(ctx context.Context, id, username, email string) (*User, error) {
+	return instrumentResult(ctx, f, "CreateUserWithUUID", func(ctx context.Context) (*User, error) {
+		return auditWrite(ctx, f, "CreateUserWithUUID", func(q querier) (*User, []int64, error) {
+			return createdUser(f.createUserWithUUID(ctx, q, id, username, email))
+		})
+	})
+}
+
+// CreateUserWithUUID creates a user with a client-generated UUID within the
+// transaction
+func (t *Tx) CreateUserWithUUID(ctx context.Context, id, username, email string) (*User, error) {
+	return txAuditWrite(ctx, t, "CreateUserWithUUID", func(q querier) (*User, []int64, error) {
+		return createdUser(t.f.createUserWithUUID(ctx, q, id, username, email))
+	})
+}
+
+// getUserByUUID validates id and looks up the user
+func (f *Frontend) getUserByUUID(ctx context.Context, q querier, id string) (*User, error) {
+	if f.schema.UUIDColumn == "" {
+		return nil, fmt.Errorf("%w: Schema.UUIDColumn is not set", ErrInvalidInput)
+	}
+	id, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return f.getUserWhere(ctx, q, f.schema.UUIDColumn, id)
+}
+
+// createUserWithUUID validates the inputs and inserts the user with its UUID
+func (f *Frontend) createUserWithUUID(ctx context.Context, q querier, id, username, email string) (*User, error) {
+	if f.schema.UUIDColumn == "" {
+		return nil, fmt.Errorf("%w: Schema.UUIDColumn is not set", ErrInvalidInput)
+	}
+	id, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.validateNewUsername(username); err != nil {
+		return nil, err
+	}
+	if err := f.validateNewEmail(email); err != nil {
+		return nil, err
+	}
+
+	extra := []columnValue{{column: f.schema.UUIDColumn, value: id}}
+	return f.insertUser(ctx, q, username, email, extra)
+}
+
+// parseUUID checks that id is a UUID in canonical hyphenated form and returns
+// it lowercased, the form PostgreSQL's uuid type prints
+func parseUUID(id string) (string, error) {
+	if len(id) != 36 {
+		return "", fmt.Errorf("%w: invalid UUID", ErrInvalidInput)
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return "", fmt.Errorf("%w: invalid UUID", ErrInvalidInput)
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return "", fmt.Errorf("%w: invalid UUID", ErrInvalidInput)
+			}
+		}
+	}
+	return strings.ToLower(id), nil
+}
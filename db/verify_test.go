@@ -0,0 +1,20 @@
+package db
+
+import "testing"
+
+func TestVerificationResetClearsSentAt(t *testing.T) {
+	db, _ := newFakeDB(t)
+	config := DefaultConfig()
+	config.EmailVerification = true
+	f, err := NewFrontendWithDB(db, config)
+	if err != nil {
+		t.Fatalf("NewFrontendWithDB: %v", err)
+	}
+
+	want := `email_verified = (email_verified AND email = $2), ` +
+		`verification_token = CASE WHEN email = $2 THEN verification_token ELSE NULL END, ` +
+		`verification_sent_at = CASE WHEN email = $2 THEN verification_sent_at ELSE NULL END, `
+	if got := f.verificationReset("$2"); got != want {
+		t.Errorf("verificationReset =\n  %s\nwant\n  %s", got, want)
+	}
+}
@@ -5,7 +5,6 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"os"
@@ -110,13 +109,16 @@ func main() {
 
 	// Example 10: Transaction example
 	log.Println("\n--- Transaction Example ---")
-	err = frontend.ExecuteInTransaction(ctx, func(tx *sql.Tx) error {
+	err = frontend.ExecuteInTransaction(ctx, func(tx *db.Tx) error {
 		// Multiple operations in a transaction
 		log.Println("  Executing operations in transaction...")
-		
+
+		// Tx exposes the same validated operations as the frontend.
 		// If any operation fails, entire transaction is rolled back
-		// Add your transaction operations here
-		
+		if _, err := tx.SearchUsers(ctx, "john", 10); err != nil {
+			return err
+		}
+
 		return nil // Commit transaction
 	})
 	if err != nil {